@@ -0,0 +1,841 @@
+package bigarray
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+// Arrow IPC constants, from the Arrow format's Schema.fbs and Message.fbs.
+const (
+	arrowContinuation = 0xffffffff
+	arrowVersionV5    = 4
+
+	arrowHeaderSchema          = 1
+	arrowHeaderDictionaryBatch = 2
+	arrowHeaderRecordBatch     = 3
+
+	arrowTypeInt = 2
+
+	// arrowMaxMetadata limits the size of the metadata which ReadArrowIPC
+	// will allocate for a single message.
+	arrowMaxMetadata = 64 << 20
+)
+
+// ErrMalformedArrow is returned by ReadArrowIPC when a message cannot be
+// decoded.
+var ErrMalformedArrow = errors.New("malformed Arrow IPC message")
+
+// WriteArrowIPC writes the array to (w) as an Arrow IPC stream holding a
+// single column named (name), of type UInt8, UInt16, UInt32, or UInt64.  The
+// type is the smallest which can hold ba.MaxValue().
+//
+// The column is split into record batches of PageSize bytes each.  If
+// PageSize is not specified, it defaults to the array's own page size for
+// on-disk arrays.  Only one batch is held in memory at a time, so on-disk
+// arrays of any size are exported in bounded memory.
+//
+// If (ba) is a NullableArray, the column is nullable and null elements are
+// recorded in each batch's validity bitmap.
+//
+func WriteArrowIPC(w io.Writer, name string, ba BigArray, opts ...Option) error {
+	var o options
+	o.apply(opts...)
+	if o.pageSize == 0 {
+		impl, unlock := lockImpl(ba)
+		if x, ok := impl.(*onDiskArray); ok {
+			o.pageSize = x.psz
+		}
+		unlock()
+	}
+	bpv := calcMaxToBPV(ba.MaxValue())
+	o.populatePaging(uint(bpv), "value")
+
+	_, nullable := ba.(NullableArray)
+
+	bw := bufio.NewWriter(w)
+	if err := writeArrowMessage(bw, arrowSchema(name, bpv, nullable), nil); err != nil {
+		return err
+	}
+
+	step := uint64(o.pageSize) / uint64(bpv)
+	data := make([]byte, step*uint64(bpv))
+	valid := make([]byte, (step+7)/8)
+	body := make([]byte, 0, arrowPad(uint64(len(valid)))+uint64(len(data)))
+
+	num := ba.Len()
+	iter := ba.Iterate(0, num)
+	for off := uint64(0); off < num; off += step {
+		n := num - off
+		if n > step {
+			n = step
+		}
+		nulls := uint64(0)
+		for k := range valid {
+			valid[k] = 0
+		}
+		for k := uint64(0); k < n && iter.Next(); k++ {
+			if iter.Valid() {
+				valid[k/8] |= 1 << (k % 8)
+			} else {
+				nulls++
+			}
+			bpvEncode(bpv, data[k*uint64(bpv):], iter.Value())
+		}
+		if err := iter.Err(); err != nil {
+			iter.Close()
+			return err
+		}
+
+		body = body[:0]
+		var validLen uint64
+		if nulls != 0 {
+			validLen = (n + 7) / 8
+			body = append(body, valid[0:validLen]...)
+			body = append(body, make([]byte, arrowPad(validLen)-validLen)...)
+		}
+		dataOff := uint64(len(body))
+		dataLen := n * uint64(bpv)
+		body = append(body, data[0:dataLen]...)
+		body = append(body, make([]byte, arrowPad(dataLen)-dataLen)...)
+
+		meta := arrowRecordBatch(n, nulls, validLen, dataOff, dataLen, uint64(len(body)))
+		if err := writeArrowMessage(bw, meta, body); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	var eos [8]byte
+	binary.LittleEndian.PutUint32(eos[0:4], arrowContinuation)
+	if _, err := bw.Write(eos[:]); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ReadArrowIPC constructs a new frozen array from an Arrow IPC stream read
+// from (r).
+//
+// The stream's schema must hold a single column of type UInt8, UInt16,
+// UInt32, or UInt64.  The array is built with a Builder, so the options are
+// interpreted as for NewBuilder, except that BytesPerValue defaults to the
+// width of the column.
+//
+// If any record batch contains nulls, the array is a NullableArray whose null
+// elements are read from the batches' validity bitmaps.  Otherwise it is an
+// ordinary array, even if the column is declared nullable.
+//
+// Message bodies are streamed rather than read into memory whole, so a batch
+// of any size is read in bounded memory.
+//
+func ReadArrowIPC(r io.Reader, opts ...Option) (BigArray, error) {
+	br := bufio.NewReader(r)
+
+	meta, bodyLen, err := readArrowMessage(br)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	bpv, err := parseArrowSchema(meta)
+	if err != nil {
+		return nil, err
+	}
+	if err := skipArrowBytes(br, bodyLen); err != nil {
+		return nil, err
+	}
+
+	b := NewBuilder(append([]Option{BytesPerValue(bpv)}, opts...)...)
+	defer b.Close()
+
+	// The validity bitset is only built once a batch with nulls turns up.
+	var nulls *arrowNulls
+	defer func() {
+		if nulls != nil {
+			nulls.b.Close()
+		}
+	}()
+
+	values := make([]uint64, copyChunkSize)
+	raw := make([]byte, len(values)*int(bpv))
+	for {
+		meta, bodyLen, err := readArrowMessage(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		batch, err := parseArrowRecordBatch(meta, bodyLen, bpv)
+		if err != nil {
+			return nil, err
+		}
+		if batch.nulls != 0 && nulls == nil {
+			nulls = newArrowNulls(opts)
+			if err := nulls.addValid(b.Len()); err != nil {
+				return nil, err
+			}
+		}
+
+		pos := int64(0)
+		switch {
+		case batch.nulls != 0:
+			if err := skipArrowBytes(br, batch.validOff); err != nil {
+				return nil, err
+			}
+			end := nulls.num + uint64(batch.length)
+			for left := batch.validSize; left != 0; {
+				n := left
+				if n > int64(len(raw)) {
+					n = int64(len(raw))
+				}
+				if _, err := io.ReadFull(br, raw[0:n]); err != nil {
+					return nil, unexpectedEOF(err)
+				}
+				if err := nulls.addBitmap(raw[0:n], end); err != nil {
+					return nil, err
+				}
+				left -= n
+			}
+			pos = batch.validOff + batch.validSize
+		case nulls != nil:
+			if err := nulls.addValid(uint64(batch.length)); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := skipArrowBytes(br, batch.dataOff-pos); err != nil {
+			return nil, err
+		}
+		for left := batch.dataSize; left != 0; {
+			n := left
+			if n > int64(len(raw)) {
+				n = int64(len(raw))
+			}
+			if _, err := io.ReadFull(br, raw[0:n]); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			chunk := values[0 : n/int64(bpv)]
+			rawDecode(bpv, raw, chunk)
+			if err := b.AddMany(chunk...); err != nil {
+				return nil, err
+			}
+			left -= n
+		}
+		if err := skipArrowBytes(br, bodyLen-batch.dataOff-batch.dataSize); err != nil {
+			return nil, err
+		}
+	}
+	if nulls == nil {
+		return b.Finish()
+	}
+	return nulls.finish(b, opts)
+}
+
+// arrowNulls assembles the validity bitset of a NullableArray, one byte at a
+// time, from the validity bitmaps of successive record batches.
+type arrowNulls struct {
+	b   *Builder
+	num uint64
+	cur byte
+}
+
+func newArrowNulls(opts []Option) *arrowNulls {
+	opts = append(append([]Option(nil), opts...), BytesPerValue(1), MaxValue(0xff))
+	return &arrowNulls{b: NewBuilder(opts...)}
+}
+
+// add records whether the next element is valid.
+func (an *arrowNulls) add(valid bool) error {
+	if valid {
+		an.cur |= 1 << (an.num % 8)
+	}
+	an.num++
+	if an.num%8 != 0 {
+		return nil
+	}
+	value := an.cur
+	an.cur = 0
+	return an.b.Add(uint64(value))
+}
+
+// addValid records (n) valid elements.
+func (an *arrowNulls) addValid(n uint64) error {
+	for ; n != 0 && an.num%8 != 0; n-- {
+		if err := an.add(true); err != nil {
+			return err
+		}
+	}
+	for ; n >= 8; n -= 8 {
+		if err := an.b.Add(0xff); err != nil {
+			return err
+		}
+		an.num += 8
+	}
+	for ; n != 0; n-- {
+		if err := an.add(true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addBitmap records the elements described by a chunk of a validity bitmap,
+// ignoring any padding bits past the element at index (end-1).
+func (an *arrowNulls) addBitmap(bitmap []byte, end uint64) error {
+	for k := uint64(0); k < 8*uint64(len(bitmap)) && an.num < end; k++ {
+		if err := an.add(bitmap[k/8]&(1<<(k%8)) != 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finish combines the values from (b) with the validity bitset into a
+// NullableArray.
+func (an *arrowNulls) finish(b *Builder, opts []Option) (BigArray, error) {
+	if an.num%8 != 0 {
+		if err := an.b.Add(uint64(an.cur)); err != nil {
+			return nil, err
+		}
+	}
+	bits, err := an.b.Finish()
+	if err != nil {
+		return nil, err
+	}
+	data, err := b.Finish()
+	if err != nil {
+		bits.Close()
+		return nil, err
+	}
+
+	var o options
+	o.apply(opts...)
+	ba := &nullableArray{data: data, bits: bits, o: o}
+	if _, ok := data.(MigratableArray); ok {
+		if _, ok := bits.(MigratableArray); ok {
+			return &migratableNullableArray{ba}, nil
+		}
+	}
+	return ba, nil
+}
+
+func arrowPad(n uint64) uint64 {
+	return (n + 7) &^ 7
+}
+
+// writeArrowMessage writes an encapsulated message: the continuation marker,
+// the size of the metadata, the metadata padded to 8 bytes, and the body.
+func writeArrowMessage(w io.Writer, meta []byte, body []byte) error {
+	size := arrowPad(uint64(len(meta)))
+	var prefix [8]byte
+	binary.LittleEndian.PutUint32(prefix[0:4], arrowContinuation)
+	binary.LittleEndian.PutUint32(prefix[4:8], uint32(size))
+	if _, err := w.Write(prefix[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(meta); err != nil {
+		return err
+	}
+	if _, err := w.Write(make([]byte, size-uint64(len(meta)))); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// readArrowMessage reads the metadata of an encapsulated message, returning
+// the metadata and the length of the body which follows it.  The body is left
+// unread.  It returns io.EOF at the end-of-stream marker.
+func readArrowMessage(r io.Reader) ([]byte, int64, error) {
+	var tmp [4]byte
+	if _, err := io.ReadFull(r, tmp[:]); err != nil {
+		return nil, 0, err
+	}
+	size := binary.LittleEndian.Uint32(tmp[:])
+	if size == arrowContinuation {
+		if _, err := io.ReadFull(r, tmp[:]); err != nil {
+			return nil, 0, unexpectedEOF(err)
+		}
+		size = binary.LittleEndian.Uint32(tmp[:])
+	}
+	if size == 0 {
+		return nil, 0, io.EOF
+	}
+	if size > arrowMaxMetadata {
+		return nil, 0, ErrMalformedArrow
+	}
+
+	meta := make([]byte, size)
+	if _, err := io.ReadFull(r, meta); err != nil {
+		return nil, 0, unexpectedEOF(err)
+	}
+	var bodyLen int64
+	err := fbParse(func() {
+		bodyLen = fbRoot(meta).int64(3, 0)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if bodyLen < 0 {
+		return nil, 0, ErrMalformedArrow
+	}
+	return meta, bodyLen, nil
+}
+
+// skipArrowBytes discards (n) bytes of a message body.
+func skipArrowBytes(r io.Reader, n int64) error {
+	if _, err := io.CopyN(ioutil.Discard, r, n); err != nil {
+		return unexpectedEOF(err)
+	}
+	return nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// arrowSchema encodes a Message holding a Schema with one unsigned integer
+// Field.
+func arrowSchema(name string, bpv byte, nullable bool) []byte {
+	var b fbBuilder
+	root := b.offset()
+
+	msg, msgSlots := b.table(
+		fbScalar(2, arrowVersionV5),
+		fbScalar(1, arrowHeaderSchema),
+		fbOffset(),
+		fbScalar(8, 0))
+	b.patch(root, msg)
+
+	schema, schemaSlots := b.table(
+		fbScalar(2, 0),
+		fbOffset())
+	b.patch(msgSlots[2], schema)
+
+	fields := b.offsetVector(1)
+	b.patch(schemaSlots[1], fields)
+
+	var isNullable uint64
+	if nullable {
+		isNullable = 1
+	}
+	field, slots := b.table(
+		fbOffset(),
+		fbScalar(1, isNullable),
+		fbScalar(1, arrowTypeInt),
+		fbOffset(),
+		fbAbsent(),
+		fbOffset())
+	b.patch(fields+4, field)
+	b.patch(slots[0], b.str(name))
+
+	intType, _ := b.table(
+		fbScalar(4, uint64(bpv)*8),
+		fbScalar(1, 0))
+	b.patch(slots[3], intType)
+	b.patch(slots[5], b.offsetVector(0))
+
+	return b.buf
+}
+
+// arrowRecordBatch encodes a Message holding a RecordBatch with one
+// FieldNode and two Buffers: the validity bitmap and the data.
+func arrowRecordBatch(n, nulls, validLen, dataOff, dataLen, bodyLen uint64) []byte {
+	var b fbBuilder
+	root := b.offset()
+
+	msg, msgSlots := b.table(
+		fbScalar(2, arrowVersionV5),
+		fbScalar(1, arrowHeaderRecordBatch),
+		fbOffset(),
+		fbScalar(8, bodyLen))
+	b.patch(root, msg)
+
+	batch, slots := b.table(
+		fbScalar(8, n),
+		fbOffset(),
+		fbOffset())
+	b.patch(msgSlots[2], batch)
+
+	b.patch(slots[1], b.structVector(n, nulls))
+	b.patch(slots[2], b.structVector(0, validLen, dataOff, dataLen))
+
+	return b.buf
+}
+
+// parseArrowSchema decodes a Schema message and returns the width of its
+// column.
+func parseArrowSchema(meta []byte) (byte, error) {
+	var (
+		headerType uint64
+		numFields  int
+		typeType   uint64
+		bitWidth   int64
+		signed     bool
+		endianness int64
+	)
+	err := fbParse(func() {
+		msg := fbRoot(meta)
+		headerType = msg.uint(1, 1, 0)
+		if headerType != arrowHeaderSchema {
+			return
+		}
+		schema := msg.table(2)
+		endianness = int64(int16(schema.uint(0, 2, 0)))
+		fields, n := schema.vector(1)
+		numFields = n
+		if n != 1 {
+			return
+		}
+		field := fbTableAt(meta, fields)
+		typeType = field.uint(2, 1, 0)
+		if typeType != arrowTypeInt {
+			return
+		}
+		intType := field.table(3)
+		bitWidth = int64(int32(intType.uint(0, 4, 0)))
+		signed = intType.uint(1, 1, 0) != 0
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case headerType != arrowHeaderSchema:
+		return 0, fmt.Errorf("expected Arrow Schema message, got header type %d", headerType)
+	case endianness != 0:
+		return 0, errors.New("big-endian Arrow streams are not supported")
+	case numFields != 1:
+		return 0, fmt.Errorf("expected exactly 1 Arrow field, got %d", numFields)
+	case typeType != arrowTypeInt || signed:
+		return 0, errors.New("Arrow field must be an unsigned integer type")
+	}
+	switch bitWidth {
+	case 8, 16, 32, 64:
+		return byte(bitWidth / 8), nil
+	default:
+		return 0, fmt.Errorf("unsupported Arrow integer width %d", bitWidth)
+	}
+}
+
+// arrowBatch locates the column's buffers within the body of a RecordBatch.
+// The validity bitmap is only located if the batch has nulls.
+type arrowBatch struct {
+	length    int64
+	nulls     int64
+	validOff  int64
+	validSize int64
+	dataOff   int64
+	dataSize  int64
+}
+
+// parseArrowRecordBatch decodes a RecordBatch message and locates the bytes
+// within its body which hold the column's validity bitmap and values.
+func parseArrowRecordBatch(meta []byte, bodyLen int64, bpv byte) (arrowBatch, error) {
+	var (
+		headerType  uint64
+		length      int64
+		nodes       []int64
+		buffers     []int64
+		compression bool
+	)
+	err := fbParse(func() {
+		msg := fbRoot(meta)
+		headerType = msg.uint(1, 1, 0)
+		if headerType != arrowHeaderRecordBatch {
+			return
+		}
+		batch := msg.table(2)
+		length = batch.int64(0, 0)
+		nodes = batch.structs(1, 2)
+		buffers = batch.structs(2, 2)
+		compression = batch.present(3)
+	})
+	if err != nil {
+		return arrowBatch{}, err
+	}
+
+	switch {
+	case headerType == arrowHeaderDictionaryBatch:
+		return arrowBatch{}, errors.New("Arrow dictionary batches are not supported")
+	case headerType != arrowHeaderRecordBatch:
+		return arrowBatch{}, fmt.Errorf("expected Arrow RecordBatch message, got header type %d", headerType)
+	case compression:
+		return arrowBatch{}, errors.New("compressed Arrow record batches are not supported")
+	case len(nodes) != 2 || len(buffers) != 4:
+		return arrowBatch{}, ErrMalformedArrow
+	}
+
+	off, size := buffers[2], buffers[3]
+	if length < 0 || length > math.MaxInt64/int64(bpv) {
+		return arrowBatch{}, ErrMalformedArrow
+	}
+	need := length * int64(bpv)
+	if off < 0 || size < need || need > bodyLen || off > bodyLen-need {
+		return arrowBatch{}, ErrMalformedArrow
+	}
+	batch := arrowBatch{length: length, nulls: nodes[1], dataOff: off, dataSize: need}
+	if batch.nulls < 0 || batch.nulls > length {
+		return arrowBatch{}, ErrMalformedArrow
+	}
+	if batch.nulls == 0 {
+		return batch, nil
+	}
+
+	// The body is streamed, so the bitmap must come before the values, in
+	// the same order as the buffers are listed.
+	voff, vsize := buffers[0], buffers[1]
+	vneed := (length + 7) / 8
+	if voff < 0 || vsize < vneed || vneed > off || voff > off-vneed {
+		return arrowBatch{}, ErrMalformedArrow
+	}
+	batch.validOff, batch.validSize = voff, vneed
+	return batch, nil
+}
+
+// fbBuilder writes a FlatBuffer front to back.  Every object is written after
+// the object which refers to it, and uoffset slots are patched once the
+// target's position is known.
+type fbBuilder struct {
+	buf []byte
+}
+
+// fbField describes one field of a table for fbBuilder.table.
+type fbField struct {
+	size   int
+	value  uint64
+	offset bool
+	absent bool
+}
+
+func fbScalar(size int, value uint64) fbField { return fbField{size: size, value: value} }
+func fbOffset() fbField                       { return fbField{size: 4, offset: true} }
+func fbAbsent() fbField                       { return fbField{absent: true} }
+
+func (b *fbBuilder) pad(align int) {
+	for len(b.buf)%align != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+func (b *fbBuilder) put(size int, value uint64) {
+	var tmp [8]byte
+	binary.LittleEndian.PutUint64(tmp[:], value)
+	b.buf = append(b.buf, tmp[0:size]...)
+}
+
+// offset reserves a uoffset slot and returns its position.
+func (b *fbBuilder) offset() int {
+	b.pad(4)
+	pos := len(b.buf)
+	b.put(4, 0)
+	return pos
+}
+
+// patch points the uoffset slot at (slot) to the object at (target).
+func (b *fbBuilder) patch(slot, target int) {
+	binary.LittleEndian.PutUint32(b.buf[slot:], uint32(target-slot))
+}
+
+// table writes a vtable and its table, returning the position of the table
+// and the positions of any uoffset slots, indexed by field.
+func (b *fbBuilder) table(fields ...fbField) (int, []int) {
+	offs := make([]int, len(fields))
+	cursor := 4
+	for k, field := range fields {
+		if field.absent {
+			continue
+		}
+		cursor = (cursor + field.size - 1) / field.size * field.size
+		offs[k] = cursor
+		cursor += field.size
+	}
+	size := (cursor + 7) &^ 7
+
+	b.pad(2)
+	vt := len(b.buf)
+	b.put(2, uint64(4+2*len(fields)))
+	b.put(2, uint64(size))
+	for _, off := range offs {
+		b.put(2, uint64(off))
+	}
+
+	b.pad(8)
+	pos := len(b.buf)
+	b.buf = append(b.buf, make([]byte, size)...)
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(pos-vt))
+
+	slots := make([]int, len(fields))
+	for k, field := range fields {
+		switch {
+		case field.absent:
+		case field.offset:
+			slots[k] = pos + offs[k]
+		default:
+			var tmp [8]byte
+			binary.LittleEndian.PutUint64(tmp[:], field.value)
+			copy(b.buf[pos+offs[k]:], tmp[0:field.size])
+		}
+	}
+	return pos, slots
+}
+
+// str writes a string and returns its position.
+func (b *fbBuilder) str(s string) int {
+	b.pad(4)
+	pos := len(b.buf)
+	b.put(4, uint64(len(s)))
+	b.buf = append(b.buf, s...)
+	b.buf = append(b.buf, 0)
+	return pos
+}
+
+// offsetVector writes a vector of (n) uoffset slots and returns its position.
+// Slot (k) is at position pos+4+4*k.
+func (b *fbBuilder) offsetVector(n int) int {
+	b.pad(4)
+	pos := len(b.buf)
+	b.put(4, uint64(n))
+	for k := 0; k < n; k++ {
+		b.put(4, 0)
+	}
+	return pos
+}
+
+// structVector writes a vector of structs made of pairs of int64s, and
+// returns its position.
+func (b *fbBuilder) structVector(values ...uint64) int {
+	b.pad(4)
+	if len(b.buf)%8 == 0 {
+		b.put(4, 0)
+	}
+	pos := len(b.buf)
+	b.put(4, uint64(len(values)/2))
+	for _, value := range values {
+		b.put(8, value)
+	}
+	return pos
+}
+
+// fbBadOffset is the panic value used by fbTable when the buffer is too short.
+type fbBadOffset struct{}
+
+// fbParse runs (fn), converting any out-of-bounds access by fbTable into
+// ErrMalformedArrow.
+func fbParse(fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(fbBadOffset); !ok {
+				panic(r)
+			}
+			err = ErrMalformedArrow
+		}
+	}()
+	fn()
+	return nil
+}
+
+// fbTable reads a table from a FlatBuffer.  Out-of-bounds accesses panic with
+// fbBadOffset, to be recovered by fbParse.
+type fbTable struct {
+	buf []byte
+	pos int
+}
+
+func fbRead(buf []byte, pos, size int) uint64 {
+	if pos < 0 || pos+size > len(buf) || pos+size < pos {
+		panic(fbBadOffset{})
+	}
+	var tmp [8]byte
+	copy(tmp[:], buf[pos:pos+size])
+	return binary.LittleEndian.Uint64(tmp[:])
+}
+
+func fbDeref(buf []byte, pos int) int {
+	return pos + int(fbRead(buf, pos, 4))
+}
+
+func fbRoot(buf []byte) fbTable {
+	return fbTableAt(buf, 0)
+}
+
+// fbTableAt returns the table referred to by the uoffset at (pos).
+func fbTableAt(buf []byte, pos int) fbTable {
+	return fbTable{buf: buf, pos: fbDeref(buf, pos)}
+}
+
+// field returns the position of field (k), or 0 if it is absent.
+func (t fbTable) field(k int) int {
+	vt := t.pos - int(int32(fbRead(t.buf, t.pos, 4)))
+	vtsize := int(fbRead(t.buf, vt, 2))
+	if 4+2*k+2 > vtsize {
+		return 0
+	}
+	off := int(fbRead(t.buf, vt+4+2*k, 2))
+	if off == 0 {
+		return 0
+	}
+	return t.pos + off
+}
+
+func (t fbTable) present(k int) bool {
+	return t.field(k) != 0
+}
+
+func (t fbTable) uint(k int, size int, def uint64) uint64 {
+	pos := t.field(k)
+	if pos == 0 {
+		return def
+	}
+	return fbRead(t.buf, pos, size)
+}
+
+func (t fbTable) int64(k int, def int64) int64 {
+	return int64(t.uint(k, 8, uint64(def)))
+}
+
+func (t fbTable) table(k int) fbTable {
+	pos := t.field(k)
+	if pos == 0 {
+		panic(fbBadOffset{})
+	}
+	return fbTableAt(t.buf, pos)
+}
+
+// vector returns the position of the first uoffset in vector field (k), and
+// its length.
+func (t fbTable) vector(k int) (int, int) {
+	pos := t.field(k)
+	if pos == 0 {
+		return 0, 0
+	}
+	vec := fbDeref(t.buf, pos)
+	return vec + 4, int(fbRead(t.buf, vec, 4))
+}
+
+// structs returns the contents of vector field (k), whose elements are
+// structs of (width) int64s, flattened into a single slice.
+func (t fbTable) structs(k int, width int) []int64 {
+	vec, n := t.vector(k)
+	if n < 0 || n > len(t.buf)/8 {
+		panic(fbBadOffset{})
+	}
+	out := make([]int64, n*width)
+	for i := range out {
+		out[i] = int64(fbRead(t.buf, vec+8*i, 8))
+	}
+	return out
+}
@@ -0,0 +1,178 @@
+package bigarray
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"os"
+	"testing"
+)
+
+func RunArrowTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	ba, err := New(append(opts, MaxValue(100000), NumValues(10))...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+	for i := uint64(0); i < ba.Len(); i++ {
+		ba.SetValueAt(i, i*10000)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteArrowIPC(&buf, "values", ba, PageSize(12)); err != nil {
+		t.Errorf("WriteArrowIPC: error: %v", err)
+		return
+	}
+
+	dup, err := ReadArrowIPC(bytes.NewReader(buf.Bytes()), opts...)
+	if err != nil {
+		t.Errorf("ReadArrowIPC: error: %v", err)
+		return
+	}
+	defer dup.Close()
+	if expect, actual := ba.Debug(), dup.Debug(); actual != expect {
+		t.Errorf("ReadArrowIPC: expected %s, got %s", expect, actual)
+	}
+	if !dup.Frozen() {
+		t.Errorf("ReadArrowIPC: expected frozen array")
+	}
+	if expect, actual := uint64(90000), dup.MaxValue(); actual != expect {
+		t.Errorf("ReadArrowIPC: expected MaxValue %d, got %d", expect, actual)
+	}
+
+	raw := buf.Bytes()
+	if _, err := ReadArrowIPC(bytes.NewReader(raw[0:len(raw)-20]), opts...); err == nil {
+		t.Errorf("ReadArrowIPC: expected error for truncated stream, got nil")
+	}
+
+	empty, err := New(append(opts, MaxValue(5), NumValues(0))...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer empty.Close()
+	buf.Reset()
+	if err := WriteArrowIPC(&buf, "empty", empty); err != nil {
+		t.Errorf("WriteArrowIPC: error: %v", err)
+		return
+	}
+	dup2, err := ReadArrowIPC(&buf, opts...)
+	if err != nil {
+		t.Errorf("ReadArrowIPC: error: %v", err)
+		return
+	}
+	defer dup2.Close()
+	if dup2.Len() != 0 {
+		t.Errorf("ReadArrowIPC: expected empty array, got %s", dup2.Debug())
+	}
+
+	nullable, err := New(append(opts, MaxValue(5), NumValues(3), Nullable())...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer nullable.Close()
+	nullable.SetValueAt(0, 1)
+	buf.Reset()
+	if err := WriteArrowIPC(&buf, "nullable", nullable); err != nil {
+		t.Errorf("WriteArrowIPC: error: %v", err)
+		return
+	}
+	dup3, err := ReadArrowIPC(&buf, opts...)
+	if err != nil {
+		t.Errorf("ReadArrowIPC: error: %v", err)
+		return
+	}
+	defer dup3.Close()
+	if _, ok := dup3.(NullableArray); !ok {
+		t.Errorf("ReadArrowIPC: expected NullableArray, got %T", dup3)
+	}
+	if expect, actual := "[1 . .]", dup3.Debug(); actual != expect {
+		t.Errorf("ReadArrowIPC: expected %s, got %s", expect, actual)
+	}
+
+	// Nulls in a later batch leave the elements of earlier batches valid,
+	// and bitmaps need not be a whole number of bytes.
+	wide, err := New(append(opts, MaxValue(100), NumValues(21), Nullable())...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer wide.Close()
+	for i := uint64(0); i < wide.Len(); i++ {
+		if i < 14 || i%3 != 0 {
+			wide.SetValueAt(i, i)
+		}
+	}
+	buf.Reset()
+	if err := WriteArrowIPC(&buf, "wide", wide, PageSize(7)); err != nil {
+		t.Errorf("WriteArrowIPC: error: %v", err)
+		return
+	}
+	dup4, err := ReadArrowIPC(&buf, opts...)
+	if err != nil {
+		t.Errorf("ReadArrowIPC: error: %v", err)
+		return
+	}
+	defer dup4.Close()
+	if expect, actual := wide.Debug(), dup4.Debug(); actual != expect {
+		t.Errorf("ReadArrowIPC: expected %s, got %s", expect, actual)
+	}
+	if !dup4.Frozen() {
+		t.Errorf("ReadArrowIPC: expected frozen array")
+	}
+}
+
+func TestArrow_InMemory(t *testing.T) {
+	RunArrowTests(t)
+}
+
+func TestArrow_OnDisk(t *testing.T) {
+	RunArrowTests(t, OnDiskThreshold(0))
+}
+
+func TestArrow_Golden(t *testing.T) {
+	// testdata/uint16.arrows was assembled independently of WriteArrowIPC,
+	// with a back-to-front FlatBuffers encoder laid out like the Arrow C++
+	// writer: fields packed largest first, default-valued fields omitted, a
+	// nullable field with an empty validity buffer, and two record batches.
+	f, err := os.Open("testdata/uint16.arrows")
+	if err != nil {
+		t.Fatalf("os.Open: error: %v", err)
+	}
+	defer f.Close()
+
+	ba, err := ReadArrowIPC(f)
+	if err != nil {
+		t.Fatalf("ReadArrowIPC: error: %v", err)
+	}
+	defer ba.Close()
+	if expect, actual := "[0 1 300 65535 42 7 1000 5 6]", ba.Debug(); actual != expect {
+		t.Errorf("ReadArrowIPC: expected %s, got %s", expect, actual)
+	}
+	if bpv := bytesPerValueOf(ba); bpv != 2 {
+		t.Errorf("ReadArrowIPC: expected BytesPerValue 2, got %d", bpv)
+	}
+}
+
+func TestArrow_Malformed(t *testing.T) {
+	stream := func(batch []byte, body []byte) *bytes.Reader {
+		var buf bytes.Buffer
+		writeArrowMessage(&buf, arrowSchema("x", 2, false), nil)
+		writeArrowMessage(&buf, batch, body)
+		return bytes.NewReader(buf.Bytes())
+	}
+
+	huge := arrowRecordBatch(1<<62, 0, 0, 0, math.MaxInt64, 8)
+	if _, err := ReadArrowIPC(stream(huge, make([]byte, 8))); err != ErrMalformedArrow {
+		t.Errorf("ReadArrowIPC with overflowing length: expected ErrMalformedArrow, got %v", err)
+	}
+
+	long := arrowRecordBatch(4, 0, 0, 0, 8, 1<<40)
+	if _, err := ReadArrowIPC(stream(long, make([]byte, 8))); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadArrowIPC with truncated body: expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
@@ -0,0 +1,341 @@
+package bigarray
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrBlobArrayFull is returned by BlobArray.Append when the array has no room
+// for another blob, either because NumValues blobs have already been appended
+// or because the blob would push the total data size beyond MaxValue.
+var ErrBlobArrayFull = errors.New("blob array is full")
+
+// BlobArray is an append-only array of variable-length byte strings.
+//
+// The blobs are stored back to back in a data store, alongside a BigArray of
+// offsets into that store.  Both parts spill to disk according to the usual
+// OnDiskThreshold rules.
+type BlobArray struct {
+	offsets BigArray
+	data    blobData
+	num     uint64
+	psz     uint
+	ro      bool
+}
+
+// NewBlobArray constructs a BlobArray instance.
+//
+// NumValues specifies the maximum number of blobs, and MaxValue specifies the
+// maximum total size (bytes) of all blobs combined.  The width of the offsets
+// array is chosen from MaxValue.  WithFile and WithReadOnlyFile are not
+// supported.
+//
+func NewBlobArray(opts ...Option) (*BlobArray, error) {
+	var o options
+	o.apply(opts...)
+	if o.maxValue == 0 {
+		panic(errors.New("must specify MaxValue for a BlobArray"))
+	}
+	if o.backingFile != nil {
+		panic(errors.New("BlobArray does not support WithFile or WithReadOnlyFile"))
+	}
+	o.populate()
+
+	offsets, err := New(
+		NumValues(o.numValues+1),
+		MaxValue(o.maxValue),
+		BytesPerValue(calcMaxToBPV(o.maxValue)),
+		OnDiskThreshold(o.diskThreshold),
+		PageSize(o.pageSize),
+		WithPool(o.bufferPool),
+		TempDir(o.tempDir),
+		TempPrefix(o.tempPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := &BlobArray{
+		offsets: offsets,
+		data:    blobData{odt: o.diskThreshold, o: o},
+		psz:     o.pageSize,
+	}
+	return blobs, nil
+}
+
+// Frozen returns true if this array is read-only.
+func (blobs *BlobArray) Frozen() bool {
+	return blobs.ro
+}
+
+// Len returns the number of blobs in this array.
+func (blobs *BlobArray) Len() uint64 {
+	return blobs.num
+}
+
+// Cap returns the maximum number of blobs that this array can hold.
+func (blobs *BlobArray) Cap() uint64 {
+	return blobs.offsets.Len() - 1
+}
+
+// DataSize returns the total size (bytes) of all blobs in this array.
+func (blobs *BlobArray) DataSize() uint64 {
+	return blobs.data.size
+}
+
+// Append adds a blob to the end of the array.
+func (blobs *BlobArray) Append(blob []byte) error {
+	if blobs.ro {
+		panic("BlobArray is read-only")
+	}
+	if blobs.num >= blobs.Cap() {
+		return ErrBlobArrayFull
+	}
+	end := blobs.data.size + uint64(len(blob))
+	if end > blobs.offsets.MaxValue() || end < blobs.data.size {
+		return ErrBlobArrayFull
+	}
+	if err := blobs.data.append(blob); err != nil {
+		return err
+	}
+	if err := blobs.offsets.SetValueAt(blobs.num+1, end); err != nil {
+		return err
+	}
+	blobs.num++
+	return nil
+}
+
+// At returns a copy of the blob at the given index.
+//
+// On-disk arrays have very slow random access.  If your accesses are
+// sequential, you should consider using a BlobIterator.
+func (blobs *BlobArray) At(index uint64) ([]byte, error) {
+	if index >= blobs.num {
+		return nil, io.EOF
+	}
+	start, err := blobs.offsets.ValueAt(index)
+	if err != nil {
+		return nil, err
+	}
+	end, err := blobs.offsets.ValueAt(index + 1)
+	if err != nil {
+		return nil, err
+	}
+	blob := make([]byte, end-start)
+	_, err = blobs.data.ReadAt(blob, int64(start))
+	if err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+// Iterate returns a BlobIterator that starts at index (i) and stops at index
+// (j-1).
+func (blobs *BlobArray) Iterate(i, j uint64) *BlobIterator {
+	if i > j {
+		panic(fmt.Errorf("BlobArray.Iterate: i > j: i=%d j=%d", i, j))
+	}
+	if j > blobs.num {
+		j = blobs.num
+		if i > j {
+			i = j
+		}
+	}
+	return &BlobIterator{
+		blobs: blobs,
+		iter:  blobs.offsets.Iterate(i, j+1),
+		base:  i,
+		num:   (j - i),
+	}
+}
+
+// Freeze makes the array read-only and releases any unused capacity.
+func (blobs *BlobArray) Freeze() error {
+	if blobs.ro {
+		return nil
+	}
+	blobs.ro = true
+	if err := blobs.offsets.Truncate(blobs.num + 1); err != nil {
+		return err
+	}
+	return blobs.offsets.Freeze()
+}
+
+// Flush ensures that all pending writes have reached the OS.
+func (blobs *BlobArray) Flush() error {
+	return blobs.offsets.Flush()
+}
+
+// Sync is equivalent to Flush, because a BlobArray is always held in memory
+// or in temporary files, which do not outlive the process.
+func (blobs *BlobArray) Sync() error {
+	return blobs.Flush()
+}
+
+// Close flushes any writes and frees the resources used by the array.
+func (blobs *BlobArray) Close() error {
+	err := blobs.offsets.Close()
+	if err2 := blobs.data.close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// BlobIterator provides fast sequential access to a BlobArray.  It follows
+// the same usage pattern as Iterator.
+type BlobIterator struct {
+	blobs  *BlobArray
+	iter   Iterator
+	r      *bufio.Reader
+	blob   []byte
+	err    error
+	base   uint64
+	pos    uint64
+	num    uint64
+	start  uint64
+	primed bool
+}
+
+// Next advances the iterator to the next blob and returns true, or returns
+// false if the end of the iteration has been reached or if an error has
+// occurred.
+func (iter *BlobIterator) Next() bool {
+	if iter.err != nil {
+		return false
+	}
+	if !iter.primed {
+		iter.primed = true
+		if !iter.iter.Next() {
+			iter.err = iter.iter.Err()
+			return false
+		}
+		iter.start = iter.iter.Value()
+		size := iter.blobs.data.size - iter.start
+		sr := io.NewSectionReader(&iter.blobs.data, int64(iter.start), int64(size))
+		iter.r = bufio.NewReaderSize(sr, int(iter.blobs.psz))
+	} else {
+		iter.pos++
+	}
+	if iter.pos >= iter.num {
+		iter.pos = iter.num
+		iter.blob = nil
+		return false
+	}
+	if !iter.iter.Next() {
+		iter.err = iter.iter.Err()
+		if iter.err == nil {
+			iter.err = io.ErrUnexpectedEOF
+		}
+		iter.blob = nil
+		return false
+	}
+	end := iter.iter.Value()
+	size := end - iter.start
+	if uint64(cap(iter.blob)) < size {
+		iter.blob = make([]byte, size)
+	}
+	iter.blob = iter.blob[0:size]
+	if _, err := io.ReadFull(iter.r, iter.blob); err != nil {
+		iter.err = err
+		iter.blob = nil
+		return false
+	}
+	iter.start = end
+	return true
+}
+
+// Index returns the index of the current blob.
+func (iter *BlobIterator) Index() uint64 {
+	if !iter.primed {
+		panic("must call Next() before Index()")
+	}
+	if iter.pos >= iter.num {
+		panic("must not call Index() after Next() returns false")
+	}
+	return iter.base + iter.pos
+}
+
+// Blob returns the bytes of the current blob.
+//
+// The returned slice is only valid until the next call to Next() or Close().
+func (iter *BlobIterator) Blob() []byte {
+	if !iter.primed {
+		panic("must call Next() before Blob()")
+	}
+	if iter.pos >= iter.num {
+		panic("must not call Blob() after Next() returns false")
+	}
+	return iter.blob
+}
+
+// Err returns the error which caused Next() to return false.
+func (iter *BlobIterator) Err() error {
+	return iter.err
+}
+
+// Close frees the resources used by the iterator.
+func (iter *BlobIterator) Close() error {
+	if iter.iter == nil {
+		return iter.err
+	}
+	err := iter.iter.Close()
+	if iter.err != nil {
+		err = iter.err
+	}
+	*iter = BlobIterator{err: ErrClosedIterator}
+	return err
+}
+
+// blobData is a growable byte store which lives in memory until it exceeds
+// the on-disk threshold, at which point it moves to a temporary file.
+type blobData struct {
+	mem  []byte
+	f    File
+	size uint64
+	odt  uint64
+	o    options
+}
+
+func (data *blobData) append(p []byte) error {
+	end := data.size + uint64(len(p))
+	if data.f == nil && end >= data.odt {
+		f, err := createTempFile(data.o, 0)
+		if err != nil {
+			return err
+		}
+		if _, err := f.WriteAt(data.mem, 0); err != nil {
+			removeFile(f)
+			return err
+		}
+		data.f = f
+		data.mem = nil
+	}
+	if data.f != nil {
+		if _, err := data.f.WriteAt(p, int64(data.size)); err != nil {
+			return err
+		}
+	} else {
+		data.mem = append(data.mem, p...)
+	}
+	data.size = end
+	return nil
+}
+
+func (data *blobData) ReadAt(p []byte, off int64) (int, error) {
+	if data.f != nil {
+		return data.f.ReadAt(p, off)
+	}
+	return bytes.NewReader(data.mem).ReadAt(p, off)
+}
+
+func (data *blobData) close() error {
+	data.mem = nil
+	if data.f != nil {
+		f := data.f
+		data.f = nil
+		return removeFile(f)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package bigarray
+
+import (
+	"fmt"
+	"testing"
+)
+
+func RunBlobArrayBasicTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	opts = append(opts,
+		PageSize(32),
+		NumValues(40),
+		MaxValue(1000))
+
+	blobs, err := NewBlobArray(opts...)
+	if err != nil {
+		t.Errorf("NewBlobArray: error: %v", err)
+		return
+	}
+	defer blobs.Close()
+
+	expected := make([]string, 0, 40)
+	for i := 0; i < 40; i++ {
+		s := fmt.Sprintf("blob-%d", i*i)
+		if i%7 == 0 {
+			s = ""
+		}
+		if err := blobs.Append([]byte(s)); err != nil {
+			t.Errorf("BlobArray.Append %d: error: %v", i, err)
+		}
+		expected = append(expected, s)
+	}
+	if err := blobs.Append([]byte("overflow")); err != ErrBlobArrayFull {
+		t.Errorf("BlobArray.Append: expected ErrBlobArrayFull, got %v", err)
+	}
+	if 40 != blobs.Len() {
+		t.Errorf("BlobArray.Len: expected 40, got %d", blobs.Len())
+	}
+
+	for i := uint64(0); i < blobs.Len(); i++ {
+		blob, err := blobs.At(i)
+		if err != nil {
+			t.Errorf("BlobArray.At %d: error: %v", i, err)
+			continue
+		}
+		if string(blob) != expected[i] {
+			t.Errorf("BlobArray.At %d: expected %q, got %q", i, expected[i], blob)
+		}
+	}
+
+	if err := blobs.Freeze(); err != nil {
+		t.Errorf("BlobArray.Freeze: error: %v", err)
+	}
+
+	iter := blobs.Iterate(3, blobs.Len())
+	n := uint64(3)
+	for iter.Next() {
+		if iter.Index() != n {
+			t.Errorf("BlobArray.Iterate out of order: expected [%d], got [%d]", n, iter.Index())
+		}
+		if string(iter.Blob()) != expected[n] {
+			t.Errorf("BlobArray.Iterate [%d]: expected %q, got %q", n, expected[n], iter.Blob())
+		}
+		n++
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("BlobArray.Iterate: error: %v", err)
+	}
+	if n != blobs.Len() {
+		t.Errorf("BlobArray.Iterate stopped early at %d", n)
+	}
+}
+
+func TestBlobArray_InMemory(t *testing.T) {
+	RunBlobArrayBasicTests(t)
+}
+
+func TestBlobArray_OnDisk(t *testing.T) {
+	RunBlobArrayBasicTests(t, OnDiskThreshold(0))
+}
+
+func TestBlobArray_Spill(t *testing.T) {
+	RunBlobArrayBasicTests(t, OnDiskThreshold(100))
+}
@@ -0,0 +1,220 @@
+package bigarray
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// MemoryBudget limits the memory held by a group of arrays.  It is shared by
+// passing it to New with the WithMemoryBudget option.
+//
+// A MemoryBudget counts the bytes held by every in-memory array created with
+// it, plus the page buffers held by every on-disk array created with it.
+// A new array which would push the total past the limit is created on disk,
+// regardless of OnDiskThreshold.
+//
+// When the total exceeds the limit, the budget also picks in-memory arrays to
+// spill to temporary files, least recently used first, and moves them to disk
+// straight away.  An array which is in use at that moment, by another
+// goroutine or through an outstanding Iterator, is marked instead, and moves
+// itself to disk the next time it is used.
+//
+// A MemoryBudget is safe for concurrent use by multiple goroutines.
+//
+type MemoryBudget struct {
+	mu     sync.Mutex
+	limit  uint64
+	used   uint64
+	clock  uint64
+	arrays map[*dynamicArray]struct{}
+}
+
+// NewMemoryBudget returns a MemoryBudget which allows (limit) bytes.
+func NewMemoryBudget(limit uint64) *MemoryBudget {
+	return &MemoryBudget{
+		limit:  limit,
+		arrays: make(map[*dynamicArray]struct{}),
+	}
+}
+
+// Limit returns the number of bytes allowed by the budget.
+func (mb *MemoryBudget) Limit() uint64 {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	return mb.limit
+}
+
+// Used returns the number of bytes currently charged against the budget.
+func (mb *MemoryBudget) Used() uint64 {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	return mb.used
+}
+
+// SetLimit changes the number of bytes allowed by the budget.  Lowering the
+// limit below Used() spills arrays, as described above.
+func (mb *MemoryBudget) SetLimit(limit uint64) {
+	mb.mu.Lock()
+	mb.limit = limit
+	victims := mb.relieve(0)
+	mb.mu.Unlock()
+	spill(victims)
+}
+
+// reserve charges (n) bytes against the budget if they fit within the limit,
+// and returns true.  Otherwise it spills arrays, so that a later reservation
+// may succeed, and returns false.
+func (mb *MemoryBudget) reserve(n uint64) bool {
+	mb.mu.Lock()
+	if mb.used+n > mb.limit {
+		victims := mb.relieve(n)
+		mb.mu.Unlock()
+		spill(victims)
+		return false
+	}
+	mb.used += n
+	mb.mu.Unlock()
+	return true
+}
+
+// charge unconditionally charges (n) bytes against the budget.
+func (mb *MemoryBudget) charge(n uint64) {
+	if mb == nil {
+		return
+	}
+	mb.mu.Lock()
+	mb.used += n
+	victims := mb.relieve(0)
+	mb.mu.Unlock()
+	spill(victims)
+}
+
+// release returns (n) bytes to the budget.
+func (mb *MemoryBudget) release(n uint64) {
+	if mb == nil {
+		return
+	}
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	mb.used -= n
+}
+
+// track adds an array to the set which may be spilled.  (held) bytes must
+// already have been reserved on the array's behalf.
+func (mb *MemoryBudget) track(ba *dynamicArray, held uint64) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	ba.held = held
+	atomic.StoreUint64(&ba.lastUse, mb.tick())
+	mb.arrays[ba] = struct{}{}
+}
+
+// untrack removes an array from the budget and releases the bytes it held.
+func (mb *MemoryBudget) untrack(ba *dynamicArray) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	delete(mb.arrays, ba)
+	mb.used -= ba.held
+	ba.held = 0
+}
+
+// adjust changes the number of bytes held by an array, e.g. after it has been
+// widened or spilled.
+func (mb *MemoryBudget) adjust(ba *dynamicArray, held uint64) {
+	mb.mu.Lock()
+	mb.used = mb.used - ba.held + held
+	ba.held = held
+	victims := mb.relieve(0)
+	mb.mu.Unlock()
+	spill(victims)
+}
+
+// fits returns true if (n) more bytes would fit within the limit.
+func (mb *MemoryBudget) fits(n uint64) bool {
+	if mb == nil {
+		return true
+	}
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	return mb.used+n <= mb.limit
+}
+
+// tick returns the next value of the budget's logical clock.
+func (mb *MemoryBudget) tick() uint64 {
+	return atomic.AddUint64(&mb.clock, 1)
+}
+
+// relieve marks arrays to be spilled, least recently used first, until the
+// bytes they hold would bring (used + extra) within the limit, and returns
+// every marked array.  Arrays which are already marked count toward the
+// total.  mb.mu must be held; the caller passes the result to spill once it
+// has been released.
+func (mb *MemoryBudget) relieve(extra uint64) []*dynamicArray {
+	if mb.used+extra <= mb.limit {
+		return nil
+	}
+	need := mb.used + extra - mb.limit
+
+	var victims, candidates []*dynamicArray
+	for ba := range mb.arrays {
+		if ba.held == 0 {
+			continue
+		}
+		if atomic.LoadInt32(&ba.spill) != 0 {
+			victims = append(victims, ba)
+			if ba.held >= need {
+				return victims
+			}
+			need -= ba.held
+			continue
+		}
+		candidates = append(candidates, ba)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return atomic.LoadUint64(&candidates[i].lastUse) < atomic.LoadUint64(&candidates[j].lastUse)
+	})
+	for _, ba := range candidates {
+		atomic.StoreInt32(&ba.spill, 1)
+		victims = append(victims, ba)
+		if ba.held >= need {
+			break
+		}
+		need -= ba.held
+	}
+	return victims
+}
+
+// spill moves marked arrays to disk.  An array whose lock is taken, or which
+// has outstanding iterators or is busy, stays marked and moves itself the next
+// time it is touched.  Only the lock is tried, never waited on, so spill is
+// safe to call while the lock of some other array is held.
+func spill(victims []*dynamicArray) {
+	for _, ba := range victims {
+		if !ba.tryLock() {
+			continue
+		}
+		if !ba.closed && ba.busy == 0 && len(ba.iters) == 0 && atomic.LoadInt32(&ba.spill) != 0 {
+			// A failed spill leaves the array intact in memory.
+			ba.demote()
+		}
+		ba.unlock()
+	}
+}
+
+// inMemoryBytes returns the number of bytes allocated by an in-memory array,
+// or 0 for any other kind of array.
+func inMemoryBytes(ba BigArray) uint64 {
+	switch x := ba.(type) {
+	case *inMemoryArray8:
+		return uint64(cap(x.data))
+	case *inMemoryArray16:
+		return 2 * uint64(cap(x.data))
+	case *inMemoryArray32:
+		return 4 * uint64(cap(x.data))
+	case *inMemoryArray64:
+		return 8 * uint64(cap(x.data))
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,254 @@
+package bigarray
+
+import (
+	"testing"
+)
+
+func newBudgetedArray(t *testing.T, mb *MemoryBudget, num uint64) BigArray {
+	t.Helper()
+	ba, err := New(
+		MaxValue(255),
+		NumValues(num),
+		PageSize(16),
+		WithMemoryBudget(mb))
+	if err != nil {
+		t.Fatalf("New: error: %v", err)
+	}
+	iter := ba.Iterate(0, ba.Len())
+	for iter.Next() {
+		iter.SetValue(iter.Index() % 256)
+	}
+	if err := iter.Close(); err != nil {
+		t.Fatalf("Iterator.Close: error: %v", err)
+	}
+	return ba
+}
+
+func checkBudgetedArray(t *testing.T, name string, ba BigArray, inMemory bool) {
+	t.Helper()
+	for index := uint64(0); index < ba.Len(); index++ {
+		value, err := ba.ValueAt(index)
+		if err != nil || value != index%256 {
+			t.Errorf("%s: ValueAt %d: expected %d, got %d, err=%v", name, index, index%256, value, err)
+			return
+		}
+	}
+	if isInMemory(unwrapArray(ba)) != inMemory {
+		t.Errorf("%s: expected inMemory=%v, got %s", name, inMemory, ba.Debug())
+	}
+}
+
+func TestMemoryBudget_ForcesDisk(t *testing.T) {
+	mb := NewMemoryBudget(100)
+
+	a := newBudgetedArray(t, mb, 80)
+	defer a.Close()
+	if !isInMemory(unwrapArray(a)) {
+		t.Errorf("first array: expected in memory, got %s", a.Debug())
+	}
+	if used := mb.Used(); used != 80 {
+		t.Errorf("MemoryBudget.Used: expected 80, got %d", used)
+	}
+
+	// Creating the second array moved the first to disk to make room.
+	b := newBudgetedArray(t, mb, 50)
+	defer b.Close()
+	checkBudgetedArray(t, "second array", b, false)
+	checkBudgetedArray(t, "first array", a, false)
+	if used := mb.Used(); used != 0 {
+		t.Errorf("MemoryBudget.Used: expected 0, got %d", used)
+	}
+
+	c := newBudgetedArray(t, mb, 50)
+	checkBudgetedArray(t, "third array", c, true)
+	if used := mb.Used(); used != 50 {
+		t.Errorf("MemoryBudget.Used: expected 50, got %d", used)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("BigArray.Close: error: %v", err)
+	}
+	if used := mb.Used(); used != 0 {
+		t.Errorf("MemoryBudget.Used: expected 0 after Close, got %d", used)
+	}
+}
+
+func TestMemoryBudget_SpillsLeastRecentlyUsed(t *testing.T) {
+	mb := NewMemoryBudget(300)
+	a := newBudgetedArray(t, mb, 100)
+	defer a.Close()
+	b := newBudgetedArray(t, mb, 100)
+	defer b.Close()
+	c := newBudgetedArray(t, mb, 100)
+	defer c.Close()
+
+	a.ValueAt(0)
+	c.ValueAt(0)
+
+	other := b.Iterate(0, b.Len())
+	if !other.Skip(10) {
+		t.Fatalf("Iterator.Skip: error: %v", other.Err())
+	}
+	a.ValueAt(0)
+	c.ValueAt(0)
+
+	mb.SetLimit(250)
+	checkBudgetedArray(t, "a", a, true)
+	checkBudgetedArray(t, "c", c, true)
+	checkBudgetedArray(t, "b", b, false)
+	if other.Index() != 9 || !other.Next() || other.Value() != 10 {
+		t.Errorf("migrated Iterator: expected [10]=10, got err=%v", other.Err())
+	}
+	if err := other.Close(); err != nil {
+		t.Errorf("migrated Iterator.Close: error: %v", err)
+	}
+	if used := mb.Used(); used != 200 {
+		t.Errorf("MemoryBudget.Used: expected 200, got %d", used)
+	}
+}
+
+func TestMemoryBudget_SpillsIdleArrays(t *testing.T) {
+	mb := NewMemoryBudget(300)
+	a := newBudgetedArray(t, mb, 100)
+	defer a.Close()
+	b := newBudgetedArray(t, mb, 100)
+	defer b.Close()
+
+	mb.SetLimit(150)
+	if used := mb.Used(); used != 100 {
+		t.Errorf("MemoryBudget.Used: expected 100 before touching the array, got %d", used)
+	}
+	if a.(MigratableArray).InMemory() {
+		t.Errorf("a: expected on disk before it was touched again")
+	}
+	checkBudgetedArray(t, "a", a, false)
+	checkBudgetedArray(t, "b", b, true)
+}
+
+func TestMemoryBudget_Concurrent(t *testing.T) {
+	mb := NewMemoryBudget(1000)
+	a := newBudgetedArray(t, mb, 100)
+	defer a.Close()
+
+	// Another goroutine repeatedly squeezes the budget, which moves (a)
+	// to disk whenever it is idle.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for n := 0; n < 50; n++ {
+			b, err := New(MaxValue(255), NumValues(100), WithMemoryBudget(mb))
+			if err != nil {
+				t.Errorf("New: error: %v", err)
+				return
+			}
+			mb.SetLimit(150)
+			b.Close()
+			mb.SetLimit(1000)
+		}
+	}()
+	for n := 0; n < 50; n++ {
+		for index := uint64(0); index < a.Len(); index++ {
+			if value, err := a.ValueAt(index); err != nil || value != index%256 {
+				t.Fatalf("ValueAt %d: expected %d, got %d, err=%v", index, index%256, value, err)
+			}
+		}
+		if err := a.(MigratableArray).Promote(); err != nil {
+			t.Fatalf("Promote: error: %v", err)
+		}
+	}
+	<-done
+}
+
+func TestMemoryBudget_PageCache(t *testing.T) {
+	mb := NewMemoryBudget(1024)
+	ba, err := New(
+		MaxValue(255),
+		NumValues(64),
+		PageSize(16),
+		OnDiskThreshold(0),
+		WithMemoryBudget(mb))
+	if err != nil {
+		t.Fatalf("New: error: %v", err)
+	}
+	defer ba.Close()
+
+	iter := ba.Iterate(0, ba.Len())
+	if !iter.Next() {
+		t.Fatalf("Iterator.Next: error: %v", iter.Err())
+	}
+	if used := mb.Used(); used != 16 {
+		t.Errorf("MemoryBudget.Used: expected 16 with a live page, got %d", used)
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("Iterator.Close: error: %v", err)
+	}
+	if used := mb.Used(); used != 0 {
+		t.Errorf("MemoryBudget.Used: expected 0, got %d", used)
+	}
+}
+
+func TestMemoryBudget_DropPrefix(t *testing.T) {
+	mb := NewMemoryBudget(1000)
+	ba := newBudgetedArray(t, mb, 80)
+	defer ba.Close()
+
+	if err := DropPrefix(ba, 30); err != nil {
+		t.Fatalf("DropPrefix: error: %v", err)
+	}
+	// The array was resliced, but the dropped elements are still allocated.
+	if x, ok := unwrapArray(ba).(*inMemoryArray8); !ok || cap(x.data) != 50 {
+		t.Errorf("DropPrefix: expected a resliced in-memory array, got %s", ba.Debug())
+	}
+	if used := mb.Used(); used != 80 {
+		t.Errorf("MemoryBudget.Used: expected 80 after DropPrefix, got %d", used)
+	}
+	if value, err := ba.ValueAt(0); err != nil || value != 30 {
+		t.Errorf("ValueAt 0: expected 30, got %d, err=%v", value, err)
+	}
+
+	// Growing past the end reallocates, which frees the dropped elements.
+	if err := InsertAt(ba, 50, 1, 2, 3); err != nil {
+		t.Fatalf("InsertAt: error: %v", err)
+	}
+	if used, held := mb.Used(), inMemoryBytes(unwrapArray(ba)); used != held {
+		t.Errorf("MemoryBudget.Used: expected %d after InsertAt, got %d", held, used)
+	}
+
+	if err := ba.(MigratableArray).Demote(); err != nil {
+		t.Fatalf("Demote: error: %v", err)
+	}
+	if used := mb.Used(); used != 0 {
+		t.Errorf("MemoryBudget.Used: expected 0 after Demote, got %d", used)
+	}
+}
+
+func TestMemoryBudget_Builder(t *testing.T) {
+	mb := NewMemoryBudget(1000)
+	b := NewBuilder(NumValues(50), WithMemoryBudget(mb))
+	for i := uint64(0); i < 50; i++ {
+		if err := b.Add(i); err != nil {
+			t.Fatalf("Builder.Add: error: %v", err)
+		}
+	}
+	ba, err := b.Finish()
+	if err != nil {
+		t.Fatalf("Builder.Finish: error: %v", err)
+	}
+	if used := mb.Used(); used != 50 {
+		t.Errorf("MemoryBudget.Used: expected 50, got %d", used)
+	}
+	if _, ok := ba.(MigratableArray); !ok {
+		t.Errorf("Builder.Finish: expected MigratableArray, got %T", ba)
+	}
+
+	mb.SetLimit(10)
+	if value, err := ba.ValueAt(7); err != nil || value != 7 {
+		t.Errorf("ValueAt 7: expected 7, got %d, err=%v", value, err)
+	}
+	if isInMemory(unwrapArray(ba)) || !ba.Frozen() {
+		t.Errorf("expected a frozen array spilled to disk, got %T", unwrapArray(ba))
+	}
+	if used := mb.Used(); used != 0 {
+		t.Errorf("MemoryBudget.Used: expected 0 after spilling, got %d", used)
+	}
+	ba.Close()
+}
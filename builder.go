@@ -0,0 +1,318 @@
+package bigarray
+
+import (
+	"errors"
+)
+
+// Builder constructs a frozen BigArray from a stream of values whose count
+// and maximum are not known in advance.
+//
+// Values are buffered in memory until the buffer reaches OnDiskThreshold, at
+// which point they are moved to a temporary file and subsequent values are
+// appended to it one page at a time.  Whether in memory or on disk, the values
+// are kept at the smallest BytesPerValue which can hold every value added so
+// far, widening as larger values arrive, so that Finish can adopt the buffer
+// or the temporary file without copying it.
+//
+type Builder struct {
+	o   options
+	mem BigArray
+	f   File
+	buf []byte
+	num uint64
+	max uint64
+	off uint64
+	bpv byte
+	end bool
+}
+
+// NewBuilder constructs a Builder instance.
+//
+// NumValues is a hint for the initial capacity of the in-memory buffer.
+// MaxValue, if specified, is an upper limit: Add returns an *OutOfRangeError
+// for any larger value.  BytesPerValue, if specified, is the minimum width of
+// the finished array, which disables narrowing below that width.
+// OnDiskThreshold, PageSize, and WithPool are interpreted as for New.  With
+// WithMemoryBudget, a finished array which is held in memory is charged to the
+// budget, and may be spilled like any other array created with it.
+// WithFile and WithReadOnlyFile are not supported.
+//
+func NewBuilder(opts ...Option) *Builder {
+	var o options
+	o.apply(opts...)
+	if o.backingFile != nil {
+		panic(errors.New("Builder does not support WithFile or WithReadOnlyFile"))
+	}
+	if o.bytesPerValue == 0 {
+		o.bytesPerValue = 1
+	}
+	calcBPVToMax(o.bytesPerValue)
+	o.populatePaging(8, "value")
+
+	bpv := uint64(o.bytesPerValue)
+	hint := o.numValues
+	if hint*bpv >= o.diskThreshold {
+		hint = o.diskThreshold / bpv
+	}
+	return &Builder{
+		o:   o,
+		mem: newBuffer(o.bytesPerValue, 0, hint),
+		bpv: o.bytesPerValue,
+	}
+}
+
+// Len returns the number of values added so far.
+func (b *Builder) Len() uint64 {
+	return b.num
+}
+
+// MaxValue returns the largest value added so far.
+func (b *Builder) MaxValue() uint64 {
+	return b.max
+}
+
+// Add appends a value to the array being built.
+func (b *Builder) Add(value uint64) error {
+	if b.end {
+		panic("Builder is finished")
+	}
+	if b.o.maxValue != 0 && value > b.o.maxValue {
+		return &OutOfRangeError{Index: b.num, Value: value, Max: b.o.maxValue}
+	}
+
+	if value > b.max {
+		b.max = value
+		if bpv := calcMaxToBPV(value); bpv > b.bpv {
+			if err := b.widen(bpv); err != nil {
+				return err
+			}
+		}
+	}
+
+	if b.f == nil && (b.num+1)*uint64(b.bpv) >= b.o.diskThreshold {
+		if err := b.spill(); err != nil {
+			return err
+		}
+	}
+
+	if b.f == nil {
+		appendBuffer(b.mem, value)
+	} else {
+		if len(b.buf)+int(b.bpv) > cap(b.buf) {
+			if err := b.flushPage(); err != nil {
+				return err
+			}
+		}
+		n := len(b.buf)
+		b.buf = b.buf[0 : n+int(b.bpv)]
+		bpvEncode(b.bpv, b.buf[n:], value)
+	}
+	b.num++
+	return nil
+}
+
+// AddMany appends several values to the array being built.
+func (b *Builder) AddMany(values ...uint64) error {
+	for _, value := range values {
+		if err := b.Add(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Finish returns the frozen array containing every value added.  Its
+// BytesPerValue is the smallest which can hold the largest value (but no
+// smaller than the BytesPerValue option), and its MaxValue() is the largest
+// value added, or the largest value for its width if every value was zero.
+//
+// The Builder cannot be used after Finish returns.
+//
+func (b *Builder) Finish() (BigArray, error) {
+	if b.end {
+		panic("Builder is finished")
+	}
+
+	max := b.max
+	if max == 0 {
+		max = calcBPVToMax(b.bpv)
+	}
+
+	if b.f != nil {
+		if err := b.flushPage(); err != nil {
+			return nil, err
+		}
+		if err := b.f.Truncate(int64(b.num * uint64(b.bpv))); err != nil {
+			return nil, err
+		}
+		ba := &onDiskArray{
+			f:     b.f,
+			p:     b.o.bufferPool,
+			cache: make(map[uint64]*cachePage),
+			st:    newIOStats(b.o.observer),
+			mb:    b.o.budget,
+			num:   b.num,
+			max:   max,
+			psz:   b.o.pageSize,
+			bpv:   b.bpv,
+			ro:    true,
+			doc:   true,
+		}
+		b.f = nil
+		b.buf = nil
+		b.end = true
+		return ba, nil
+	}
+
+	ba := b.mem
+	switch x := ba.(type) {
+	case *inMemoryArray8:
+		x.max = byte(max)
+	case *inMemoryArray16:
+		x.max = uint16(max)
+	case *inMemoryArray32:
+		x.max = uint32(max)
+	case *inMemoryArray64:
+		x.max = max
+	}
+	if err := ba.Freeze(); err != nil {
+		return nil, err
+	}
+	b.mem = nil
+	b.end = true
+
+	if mb := b.o.budget; mb != nil {
+		o := b.o
+		o.numValues = b.num
+		o.maxValue = max
+		o.bytesPerValue = b.bpv
+		dyn := &dynamicArray{
+			mu:    make(chan struct{}, 1),
+			impl:  ba,
+			o:     o,
+			iters: make(map[*dynamicIterator]struct{}),
+		}
+		mb.track(dyn, 0)
+		mb.adjust(dyn, inMemoryBytes(ba))
+		return dyn, nil
+	}
+	return ba, nil
+}
+
+// Close discards the values added so far and frees the resources used by the
+// Builder.  It is safe to call Close after Finish.
+func (b *Builder) Close() error {
+	b.end = true
+	b.mem = nil
+	b.buf = nil
+	if b.f != nil {
+		f := b.f
+		b.f = nil
+		return removeFile(f)
+	}
+	return nil
+}
+
+// spill moves the in-memory buffer to a temporary file.
+func (b *Builder) spill() error {
+	f, err := createTempFile(b.o, 0)
+	if err != nil {
+		return err
+	}
+	bpv := uint64(b.bpv)
+	buf := make([]uint64, chunkSize(b.num))
+	raw := make([]byte, uint64(len(buf))*bpv)
+	for off := uint64(0); off < b.num; off += uint64(len(buf)) {
+		chunk := buf[0:chunkSize(b.num-off)]
+		memDecode(b.mem, off, chunk)
+		rawEncode(b.bpv, raw, chunk)
+		if _, err := f.WriteAt(raw[0:uint64(len(chunk))*bpv], int64(off*bpv)); err != nil {
+			removeFile(f)
+			return err
+		}
+	}
+	b.f = f
+	b.mem = nil
+	b.off = b.num * bpv
+	b.buf = make([]byte, 0, b.o.pageSize)
+	return nil
+}
+
+// widen increases the width of the buffered values.  An in-memory buffer
+// which would reach OnDiskThreshold at the new width is spilled first, so
+// that it is widened on disk rather than copied in memory.
+func (b *Builder) widen(bpv byte) error {
+	if b.f == nil && b.num*uint64(bpv) >= b.o.diskThreshold {
+		if err := b.spill(); err != nil {
+			return err
+		}
+	}
+	if b.f == nil {
+		wide := newBuffer(bpv, b.num, b.num)
+		if err := wide.CopyFrom(b.mem); err != nil {
+			return err
+		}
+		b.mem = wide
+	}
+	if b.f != nil {
+		if err := b.flushPage(); err != nil {
+			return err
+		}
+		if err := widenFile(b.f, 0, b.num, b.bpv, bpv, b.o.pageSize); err != nil {
+			return err
+		}
+		b.off = b.num * uint64(bpv)
+	}
+	b.bpv = bpv
+	return nil
+}
+
+// flushPage appends the buffered page to the temporary file.
+func (b *Builder) flushPage() error {
+	if len(b.buf) == 0 {
+		return nil
+	}
+	if _, err := b.f.WriteAt(b.buf, int64(b.off)); err != nil {
+		return err
+	}
+	b.off += uint64(len(b.buf))
+	b.buf = b.buf[:0]
+	return nil
+}
+
+// newBuffer returns an in-memory array of (num) zeros, with room for (hint)
+// values before it must be reallocated, for appendBuffer to grow.
+func newBuffer(bpv byte, num, hint uint64) BigArray {
+	if hint < num {
+		hint = num
+	}
+	max := calcBPVToMax(bpv)
+	switch bpv {
+	case 1:
+		return &inMemoryArray8{data: make([]byte, num, hint), max: byte(max)}
+	case 2:
+		return &inMemoryArray16{data: make([]uint16, num, hint), max: uint16(max)}
+	case 4:
+		return &inMemoryArray32{data: make([]uint32, num, hint), max: uint32(max)}
+	case 8:
+		return &inMemoryArray64{data: make([]uint64, num, hint), max: max}
+	default:
+		panic("BUG")
+	}
+}
+
+// appendBuffer appends a value to an array made by newBuffer.
+func appendBuffer(ba BigArray, value uint64) {
+	switch x := ba.(type) {
+	case *inMemoryArray8:
+		x.data = append(x.data, byte(value))
+	case *inMemoryArray16:
+		x.data = append(x.data, uint16(value))
+	case *inMemoryArray32:
+		x.data = append(x.data, uint32(value))
+	case *inMemoryArray64:
+		x.data = append(x.data, value)
+	default:
+		panic("BUG")
+	}
+}
@@ -0,0 +1,165 @@
+package bigarray
+
+import (
+	"testing"
+)
+
+func RunBuilderTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	type testrow struct {
+		name   string
+		values []uint64
+		bpv    byte
+		max    uint64
+		debug  string
+	}
+
+	testdata := []testrow{
+		{"Empty", nil, 1, 255, "[]"},
+		{"Zeros", []uint64{0, 0, 0}, 1, 255, "[0 0 0]"},
+		{"Narrow", []uint64{1, 2, 3, 200}, 1, 200, "[1 2 3 200]"},
+		{"Widen16", []uint64{1, 2, 300, 4, 5}, 2, 300, "[1 2 300 4 5]"},
+		{"Widen64", []uint64{7, 70000, 8, 1 << 40, 9}, 8, 1 << 40, "[7 70000 8 1099511627776 9]"},
+	}
+
+	for _, row := range testdata {
+		b := NewBuilder(append(opts, PageSize(8))...)
+		if err := b.AddMany(row.values...); err != nil {
+			t.Errorf("%s: Builder.AddMany: error: %v", row.name, err)
+			b.Close()
+			continue
+		}
+		ba, err := b.Finish()
+		if err != nil {
+			t.Errorf("%s: Builder.Finish: error: %v", row.name, err)
+			b.Close()
+			continue
+		}
+		if !ba.Frozen() {
+			t.Errorf("%s: expected frozen array", row.name)
+		}
+		if actual := ba.MaxValue(); actual != row.max {
+			t.Errorf("%s: MaxValue: expected %d, got %d", row.name, row.max, actual)
+		}
+		if actual := ba.Debug(); actual != row.debug {
+			t.Errorf("%s: expected %s, got %s", row.name, row.debug, actual)
+		}
+		if bpv := bytesPerValueOf(ba); bpv != row.bpv {
+			t.Errorf("%s: BytesPerValue: expected %d, got %d", row.name, row.bpv, bpv)
+		}
+		if err := ba.Close(); err != nil {
+			t.Errorf("%s: BigArray.Close: error: %v", row.name, err)
+		}
+		if err := b.Close(); err != nil {
+			t.Errorf("%s: Builder.Close: error: %v", row.name, err)
+		}
+	}
+
+	b := NewBuilder(append(opts, MaxValue(10), BytesPerValue(2))...)
+	defer b.Close()
+	if err := b.AddMany(1, 2, 3); err != nil {
+		t.Errorf("Builder.AddMany: error: %v", err)
+	}
+	if err := b.Add(11); err == nil {
+		t.Errorf("Builder.Add: expected *OutOfRangeError, got nil")
+	} else if _, ok := err.(*OutOfRangeError); !ok {
+		t.Errorf("Builder.Add: expected *OutOfRangeError, got %T", err)
+	}
+	ba, err := b.Finish()
+	if err != nil {
+		t.Errorf("Builder.Finish: error: %v", err)
+		return
+	}
+	defer ba.Close()
+	if actual := ba.Debug(); actual != "[1 2 3]" {
+		t.Errorf("expected [1 2 3], got %s", actual)
+	}
+	if x, ok := ba.(*onDiskArray); ok && x.bpv != 2 {
+		t.Errorf("BytesPerValue: expected 2, got %d", x.bpv)
+	}
+	if _, ok := ba.(*inMemoryArray8); ok {
+		t.Errorf("BytesPerValue: expected 2, got 1")
+	}
+}
+
+func TestBuilder_InMemory(t *testing.T) {
+	RunBuilderTests(t)
+}
+
+func TestBuilder_OnDisk(t *testing.T) {
+	RunBuilderTests(t, OnDiskThreshold(0))
+}
+
+func TestBuilder_Spill(t *testing.T) {
+	b := NewBuilder(OnDiskThreshold(64), PageSize(16))
+	defer b.Close()
+	for i := uint64(0); i < 1000; i++ {
+		if err := b.Add(i * i); err != nil {
+			t.Errorf("Builder.Add: error: %v", err)
+			return
+		}
+	}
+	ba, err := b.Finish()
+	if err != nil {
+		t.Errorf("Builder.Finish: error: %v", err)
+		return
+	}
+	defer ba.Close()
+	x, ok := ba.(*onDiskArray)
+	if !ok {
+		t.Errorf("expected an on-disk array, got %T", ba)
+		return
+	}
+	if x.bpv != 4 {
+		t.Errorf("BytesPerValue: expected 4, got %d", x.bpv)
+	}
+	iter := ba.Iterate(0, ba.Len())
+	for iter.Next() {
+		if expect := iter.Index() * iter.Index(); iter.Value() != expect {
+			t.Errorf("BigArray.ValueAt %d: expected %d, got %d", iter.Index(), expect, iter.Value())
+			break
+		}
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("Iterator.Close: error: %v", err)
+	}
+}
+
+func TestBuilder_Threshold(t *testing.T) {
+	build := func(values ...uint64) BigArray {
+		t.Helper()
+		b := NewBuilder(OnDiskThreshold(16), PageSize(8))
+		if err := b.AddMany(values...); err != nil {
+			t.Fatalf("Builder.AddMany: error: %v", err)
+		}
+		ba, err := b.Finish()
+		if err != nil {
+			t.Fatalf("Builder.Finish: error: %v", err)
+		}
+		return ba
+	}
+
+	// The threshold counts bytes at the buffer's current width.
+	ba := build(make([]uint64, 15)...)
+	if _, ok := ba.(*inMemoryArray8); !ok {
+		t.Errorf("15 one-byte values: expected *inMemoryArray8, got %T", ba)
+	}
+	ba.Close()
+
+	ba = build(make([]uint64, 16)...)
+	if _, ok := ba.(*onDiskArray); !ok {
+		t.Errorf("16 one-byte values: expected *onDiskArray, got %T", ba)
+	}
+	ba.Close()
+
+	// Widening which would cross the threshold moves the buffer to disk.
+	ba = build(1, 2, 3, 4, 5, 6, 7, 8, 300)
+	if x, ok := ba.(*onDiskArray); !ok || x.bpv != 2 {
+		t.Errorf("widened past threshold: expected 2-byte *onDiskArray, got %s", ba.Debug())
+	}
+	if actual := ba.Debug(); actual != "[1 2 3 4 5 6 7 8 300]" {
+		t.Errorf("widened past threshold: expected [1 2 3 4 5 6 7 8 300], got %s", actual)
+	}
+	ba.Close()
+}
@@ -0,0 +1,783 @@
+// Command bigarray inspects and converts persisted big arrays.
+//
+// Arrays are read from the serialized form written by BigArray.WriteTo, or
+// from NumPy .npy files, optionally gzip-compressed.  A path of "-" means
+// standard input or standard output.
+//
+// Null elements of a nullable array are printed as "null" by dump, and are
+// placed after every value by sort.  Only the arrow format can export them.
+//
+// Usage:
+//
+//	bigarray info FILE
+//	bigarray dump [-range i:j] [-index] [-hex] FILE
+//	bigarray stats [-buckets N] FILE
+//	bigarray convert [-bpv N | -compact] [-gzip] IN OUT
+//	bigarray verify FILE
+//	bigarray sort [-gzip] IN OUT
+//	bigarray import [-format csv|npy|arrow] [-index] [-hex] [-delim D] [-gzip] IN OUT
+//	bigarray export [-format csv|npy|arrow] [-index] [-hex] [-delim D] [-name NAME] IN OUT
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"container/heap"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"math/bits"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	bigarray "github.com/team-spectre/go-bigarray"
+)
+
+const (
+	magicBigArray = "BGAR"
+	magicNPY      = "\x93NUMPY"
+	magicGzip     = "\x1f\x8b"
+
+	sortRunLen = 1 << 22
+)
+
+var errUsage = errors.New("usage")
+
+type command struct {
+	name  string
+	usage string
+	run   func(env *env, args []string) error
+}
+
+var commands = []command{
+	{"info", "FILE", cmdInfo},
+	{"dump", "[-range i:j] [-index] [-hex] FILE", cmdDump},
+	{"stats", "[-buckets N] FILE", cmdStats},
+	{"convert", "[-bpv N | -compact] [-gzip] IN OUT", cmdConvert},
+	{"verify", "FILE", cmdVerify},
+	{"sort", "[-gzip] IN OUT", cmdSort},
+	{"import", "[-format csv|npy|arrow] [-index] [-hex] [-delim D] [-gzip] IN OUT", cmdImport},
+	{"export", "[-format csv|npy|arrow] [-index] [-hex] [-delim D] [-name NAME] IN OUT", cmdExport},
+}
+
+// env holds the standard streams, so that commands can be tested.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	e := &env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(run(e, os.Args[1:]))
+}
+
+func run(e *env, args []string) int {
+	if len(args) == 0 {
+		usage(e.stderr)
+		return 2
+	}
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(e, args[1:])
+		if err == errUsage || err == flag.ErrHelp {
+			fmt.Fprintf(e.stderr, "usage: bigarray %s %s\n", cmd.name, cmd.usage)
+			return 2
+		}
+		if err != nil {
+			fmt.Fprintf(e.stderr, "bigarray %s: %v\n", cmd.name, err)
+			return 1
+		}
+		return 0
+	}
+	usage(e.stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  bigarray %s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+func newFlagSet(e *env, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {}
+	return fs
+}
+
+func parseArgs(fs *flag.FlagSet, args []string, n int) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != n {
+		return nil, errUsage
+	}
+	return fs.Args(), nil
+}
+
+// input is an opened input file, with its format sniffed from the first few
+// bytes.
+type input struct {
+	f      *os.File
+	r      *bufio.Reader
+	gz     *gzip.Reader
+	format string
+}
+
+func openInput(e *env, path string) (*input, error) {
+	in := &input{}
+	var r io.Reader = e.stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		in.f = f
+		r = f
+	}
+	in.r = bufio.NewReader(r)
+
+	if magic, _ := in.r.Peek(2); string(magic) == magicGzip {
+		gz, err := gzip.NewReader(in.r)
+		if err != nil {
+			in.Close()
+			return nil, err
+		}
+		in.gz = gz
+		in.r = bufio.NewReader(gz)
+	}
+
+	magic, _ := in.r.Peek(6)
+	switch {
+	case bytes.HasPrefix(magic, []byte(magicBigArray)):
+		in.format = "bigarray"
+	case bytes.HasPrefix(magic, []byte(magicNPY)):
+		in.format = "npy"
+	default:
+		in.Close()
+		return nil, fmt.Errorf("%s: unrecognized file format", path)
+	}
+	return in, nil
+}
+
+func (in *input) Close() error {
+	if in.gz != nil {
+		in.gz.Close()
+	}
+	if in.f != nil {
+		return in.f.Close()
+	}
+	return nil
+}
+
+// load reads an array.  Uncompressed .npy files are opened in place; all
+// other inputs are copied into a new array.
+func (in *input) load() (bigarray.BigArray, error) {
+	if in.format == "npy" {
+		if in.f != nil && in.gz == nil {
+			return bigarray.OpenNPY(bigarray.WithReadOnlyFile(in.f))
+		}
+		return bigarray.ReadNPY(in.r)
+	}
+	return bigarray.ReadFrom(in.r)
+}
+
+func loadArray(e *env, path string) (bigarray.BigArray, func(), error) {
+	in, err := openInput(e, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	ba, err := in.load()
+	if err != nil {
+		in.Close()
+		return nil, nil, err
+	}
+	cleanup := func() {
+		ba.Close()
+		in.Close()
+	}
+	return ba, cleanup, nil
+}
+
+// output is a created output file, optionally gzip-compressed.
+type output struct {
+	f  *os.File
+	w  *bufio.Writer
+	gz *gzip.Writer
+}
+
+func createOutput(e *env, path string, compress bool) (*output, error) {
+	out := &output{}
+	var w io.Writer = e.stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		out.f = f
+		w = f
+	}
+	if compress {
+		out.gz = gzip.NewWriter(w)
+		w = out.gz
+	}
+	out.w = bufio.NewWriter(w)
+	return out, nil
+}
+
+func (out *output) Write(p []byte) (int, error) {
+	return out.w.Write(p)
+}
+
+func (out *output) Close() error {
+	err := out.w.Flush()
+	if out.gz != nil {
+		if err2 := out.gz.Close(); err == nil {
+			err = err2
+		}
+	}
+	if out.f != nil {
+		if err2 := out.f.Close(); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+func saveArray(e *env, path string, ba bigarray.BigArray, compress bool) error {
+	wt, ok := ba.(io.WriterTo)
+	if !ok {
+		return fmt.Errorf("%T cannot be serialized", ba)
+	}
+	out, err := createOutput(e, path, compress)
+	if err != nil {
+		return err
+	}
+	if _, err := wt.WriteTo(out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func cmdInfo(e *env, args []string) error {
+	fs := newFlagSet(e, "info")
+	args, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	in, err := openInput(e, args[0])
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var info bigarray.Info
+	if in.format == "bigarray" {
+		info, err = bigarray.ReadInfo(in.r)
+	} else {
+		info, err = bigarray.ReadNPYInfo(in.r)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.stdout, "format:     %s\n", in.format)
+	fmt.Fprintf(e.stdout, "version:    %s\n", info.Version)
+	if in.gz != nil {
+		fmt.Fprintf(e.stdout, "compressed: gzip\n")
+	}
+	if info.Nullable {
+		fmt.Fprintf(e.stdout, "nullable:   yes\n")
+	}
+	fmt.Fprintf(e.stdout, "length:     %d\n", info.Len)
+	fmt.Fprintf(e.stdout, "bpv:        %d\n", info.BytesPerValue)
+	fmt.Fprintf(e.stdout, "max:        %d\n", info.MaxValue)
+	return nil
+}
+
+func parseRange(s string, num uint64) (uint64, uint64, error) {
+	i, j := uint64(0), num
+	if s == "" {
+		return i, j, nil
+	}
+	k := strings.IndexByte(s, ':')
+	if k < 0 {
+		return 0, 0, fmt.Errorf("invalid range %q: expected i:j", s)
+	}
+	var err error
+	if lo := s[0:k]; lo != "" {
+		if i, err = strconv.ParseUint(lo, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid range %q: %v", s, err)
+		}
+	}
+	if hi := s[k+1:]; hi != "" {
+		if j, err = strconv.ParseUint(hi, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid range %q: %v", s, err)
+		}
+	}
+	if j > num {
+		j = num
+	}
+	if i > j {
+		return 0, 0, fmt.Errorf("invalid range %q: i > j", s)
+	}
+	return i, j, nil
+}
+
+func cmdDump(e *env, args []string) error {
+	fs := newFlagSet(e, "dump")
+	rng := fs.String("range", "", "dump only elements i through j-1")
+	index := fs.Bool("index", false, "prefix each value with its index")
+	hex := fs.Bool("hex", false, "print values in hexadecimal")
+	args, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	ba, cleanup, err := loadArray(e, args[0])
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	i, j, err := parseRange(*rng, ba.Len())
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(e.stdout)
+	var line []byte
+	iter := ba.Iterate(i, j)
+	for iter.Next() {
+		line = line[:0]
+		if *index {
+			line = strconv.AppendUint(line, iter.Index(), 10)
+			line = append(line, '\t')
+		}
+		if !iter.Valid() {
+			line = append(line, "null"...)
+		} else if *hex {
+			line = append(line, "0x"...)
+			line = strconv.AppendUint(line, iter.Value(), 16)
+		} else {
+			line = strconv.AppendUint(line, iter.Value(), 10)
+		}
+		line = append(line, '\n')
+		if _, err := w.Write(line); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func cmdStats(e *env, args []string) error {
+	fs := newFlagSet(e, "stats")
+	buckets := fs.Uint64("buckets", 10, "number of histogram buckets")
+	args, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if *buckets == 0 {
+		return errUsage
+	}
+	ba, cleanup, err := loadArray(e, args[0])
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	s, err := bigarray.Summarize(ba)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "count: %d\n", s.Count)
+	if s.Nulls != 0 {
+		fmt.Fprintf(e.stdout, "nulls: %d\n", s.Nulls)
+	}
+	if s.Count == 0 {
+		return nil
+	}
+
+	min, max := s.Min, s.Max
+	sum := new(big.Int).SetUint64(s.SumHi)
+	sum.Lsh(sum, 64)
+	sum.Or(sum, new(big.Int).SetUint64(s.Sum))
+	mean := new(big.Float).Quo(new(big.Float).SetInt(sum), new(big.Float).SetUint64(s.Count))
+
+	fmt.Fprintf(e.stdout, "min:   %d\n", min)
+	fmt.Fprintf(e.stdout, "max:   %d\n", max)
+	fmt.Fprintf(e.stdout, "sum:   %s\n", sum.String())
+	fmt.Fprintf(e.stdout, "mean:  %s\n", mean.Text('f', 3))
+
+	// span is max-min+1, where 0 stands for 2^64.
+	span := max - min + 1
+	if span < *buckets && span != 0 {
+		*buckets = span
+	}
+	counts := make([]uint64, *buckets)
+	iter := ba.Iterate(0, ba.Len())
+	for iter.Next() {
+		if !iter.Valid() {
+			continue
+		}
+		hi, lo := bits.Mul64(iter.Value()-min, *buckets)
+		k := hi
+		if span != 0 {
+			k, _ = bits.Div64(hi, lo, span)
+		}
+		counts[k]++
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(e.stdout, "histogram:")
+	for k, count := range counts {
+		lo := min + bucketStart(uint64(k), *buckets, span)
+		hi := min + bucketStart(uint64(k+1), *buckets, span) - 1
+		fmt.Fprintf(e.stdout, "  [%d, %d]: %d\n", lo, hi, count)
+	}
+	return nil
+}
+
+// bucketStart returns the smallest offset from the minimum which falls into
+// bucket (k).
+func bucketStart(k, buckets, span uint64) uint64 {
+	if k == buckets {
+		return span
+	}
+	if span == 0 {
+		// ceil(k * 2^64 / buckets)
+		q, r := bits.Div64(k, 0, buckets)
+		if r != 0 {
+			q++
+		}
+		return q
+	}
+	hi, lo := bits.Mul64(k, span)
+	q, r := bits.Div64(hi, lo, buckets)
+	if r != 0 {
+		q++
+	}
+	return q
+}
+
+func cmdConvert(e *env, args []string) error {
+	fs := newFlagSet(e, "convert")
+	bpv := fs.Uint("bpv", 0, "new width in bytes: 1, 2, 4, or 8")
+	compact := fs.Bool("compact", false, "use the smallest width which holds every value")
+	compress := fs.Bool("gzip", false, "compress the output")
+	args, err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	if *bpv != 0 && *compact {
+		return errUsage
+	}
+	switch *bpv {
+	case 0, 1, 2, 4, 8:
+	default:
+		return fmt.Errorf("invalid -bpv %d", *bpv)
+	}
+
+	ba, cleanup, err := loadArray(e, args[0])
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	dst := ba
+	switch {
+	case *compact:
+		dst, err = bigarray.Compact(ba)
+	case *bpv != 0:
+		dst, err = bigarray.Convert(ba, bigarray.BytesPerValue(uint8(*bpv)))
+	}
+	if err != nil {
+		return err
+	}
+	if dst != ba {
+		defer dst.Close()
+	}
+	return saveArray(e, args[1], dst, *compress)
+}
+
+func cmdVerify(e *env, args []string) error {
+	fs := newFlagSet(e, "verify")
+	args, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	in, err := openInput(e, args[0])
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if in.format != "bigarray" {
+		return fmt.Errorf("%s: %s files have no checksum", args[0], in.format)
+	}
+	ba, err := bigarray.ReadFrom(in.r)
+	if err != nil {
+		return err
+	}
+	defer ba.Close()
+	if n, _ := in.r.Read(make([]byte, 1)); n != 0 {
+		return fmt.Errorf("%s: trailing data after checksum", args[0])
+	}
+	fmt.Fprintf(e.stdout, "%s: OK\n", args[0])
+	return nil
+}
+
+func cmdSort(e *env, args []string) error {
+	fs := newFlagSet(e, "sort")
+	compress := fs.Bool("gzip", false, "compress the output")
+	args, err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	ba, cleanup, err := loadArray(e, args[0])
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	sorted, err := sortArray(ba)
+	if err != nil {
+		return err
+	}
+	defer sorted.Close()
+	return saveArray(e, args[1], sorted, *compress)
+}
+
+// sortArray sorts (ba) with an external merge sort: runs of sortRunLen
+// values are sorted in memory and spilled to disk, then merged into an on-disk
+// array with the same MaxValue as (ba).  If (ba) is nullable, so is the result,
+// with its nulls after every value.
+func sortArray(ba bigarray.BigArray) (bigarray.BigArray, error) {
+	num := ba.Len()
+	_, nullable := ba.(bigarray.NullableArray)
+	var runs []bigarray.BigArray
+	defer func() {
+		for _, run := range runs {
+			run.Close()
+		}
+	}()
+
+	buf := make([]uint64, 0, sortRunLen)
+	for i := uint64(0); i < num; i += sortRunLen {
+		j := i + sortRunLen
+		if j > num {
+			j = num
+		}
+		buf = buf[:0]
+		iter := ba.Iterate(i, j)
+		for iter.Next() {
+			if iter.Valid() {
+				buf = append(buf, iter.Value())
+			}
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+		sort.Slice(buf, func(a, b int) bool { return buf[a] < buf[b] })
+
+		b := bigarray.NewBuilder(
+			bigarray.MaxValue(ba.MaxValue()),
+			bigarray.OnDiskThreshold(0))
+		if err := b.AddMany(buf...); err != nil {
+			b.Close()
+			return nil, err
+		}
+		run, err := b.Finish()
+		if err != nil {
+			b.Close()
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	h := make(mergeHeap, 0, len(runs))
+	defer func() {
+		// The runs cannot be closed while their iterators are open.
+		for _, iter := range h {
+			iter.Close()
+		}
+	}()
+	for _, run := range runs {
+		iter := run.Iterate(0, run.Len())
+		if iter.Next() {
+			h = append(h, iter)
+		} else if err := iter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	heap.Init(&h)
+
+	opts := []bigarray.Option{
+		bigarray.MaxValue(ba.MaxValue()),
+		bigarray.NumValues(num),
+		bigarray.OnDiskThreshold(0),
+	}
+	if nullable {
+		// Every element starts out null, so the nulls need no writing.
+		opts = append(opts, bigarray.Nullable())
+	}
+	out, err := bigarray.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	w := out.Iterate(0, num)
+	for len(h) != 0 {
+		iter := h[0]
+		w.Next()
+		w.SetValue(iter.Value())
+		if iter.Next() {
+			heap.Fix(&h, 0)
+			continue
+		}
+		heap.Pop(&h)
+		if err := iter.Close(); err != nil {
+			w.Close()
+			out.Close()
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		out.Close()
+		return nil, err
+	}
+	return out, nil
+}
+
+// mergeHeap is a min-heap of Iterators, ordered by their current Value().
+type mergeHeap []bigarray.Iterator
+
+func (h mergeHeap) Len() int            { return len(h) }
+func (h mergeHeap) Less(a, b int) bool  { return h[a].Value() < h[b].Value() }
+func (h mergeHeap) Swap(a, b int)       { h[a], h[b] = h[b], h[a] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(bigarray.Iterator)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[0 : len(old)-1]
+	return x
+}
+
+// textFlags registers the flags shared by import and export.
+type textFlags struct {
+	format *string
+	index  *bool
+	hex    *bool
+	delim  *string
+}
+
+func addTextFlags(fs *flag.FlagSet) textFlags {
+	return textFlags{
+		format: fs.String("format", "csv", "file format: csv, npy, or arrow"),
+		index:  fs.Bool("index", false, "csv: each line starts with the index"),
+		hex:    fs.Bool("hex", false, "csv: values are hexadecimal"),
+		delim:  fs.String("delim", ",", "csv: delimiter after the index"),
+	}
+}
+
+func (tf textFlags) options() []bigarray.TextOption {
+	opts := []bigarray.TextOption{bigarray.Delimiter(*tf.delim)}
+	if *tf.index {
+		opts = append(opts, bigarray.IndexColumn())
+	}
+	if *tf.hex {
+		opts = append(opts, bigarray.Hex())
+	}
+	return opts
+}
+
+func cmdImport(e *env, args []string) error {
+	fs := newFlagSet(e, "import")
+	tf := addTextFlags(fs)
+	compress := fs.Bool("gzip", false, "compress the output")
+	args, err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = e.stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var ba bigarray.BigArray
+	switch *tf.format {
+	case "csv":
+		ba, err = bigarray.ReadText(r, tf.options()...)
+	case "npy":
+		ba, err = bigarray.ReadNPY(r)
+	case "arrow":
+		ba, err = bigarray.ReadArrowIPC(r)
+	default:
+		return fmt.Errorf("unknown format %q", *tf.format)
+	}
+	if err != nil {
+		return err
+	}
+	defer ba.Close()
+	return saveArray(e, args[1], ba, *compress)
+}
+
+func cmdExport(e *env, args []string) error {
+	fs := newFlagSet(e, "export")
+	tf := addTextFlags(fs)
+	name := fs.String("name", "values", "arrow: column name")
+	args, err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	ba, cleanup, err := loadArray(e, args[0])
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if _, ok := ba.(bigarray.NullableArray); ok && *tf.format != "arrow" {
+		return fmt.Errorf("%s: nulls can only be exported in the arrow format", args[0])
+	}
+
+	out, err := createOutput(e, args[1], false)
+	if err != nil {
+		return err
+	}
+	switch *tf.format {
+	case "csv":
+		err = bigarray.WriteText(out, ba, tf.options()...)
+	case "npy":
+		err = bigarray.WriteNPY(out, ba)
+	case "arrow":
+		err = bigarray.WriteArrowIPC(out, *name, ba)
+	default:
+		err = fmt.Errorf("unknown format %q", *tf.format)
+	}
+	if err2 := out.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
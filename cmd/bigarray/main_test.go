@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bigarray "github.com/team-spectre/go-bigarray"
+)
+
+func runCommand(t *testing.T, stdin string, args ...string) (string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	e := &env{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}
+	code := run(e, args)
+	if code != 0 {
+		return stderr.String(), code
+	}
+	return stdout.String(), code
+}
+
+// failWriter is an io.Writer which always fails.
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bigarray")
+	if err != nil {
+		t.Fatalf("TempDir: error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	type testrow struct {
+		args   []string
+		stdin  string
+		expect string
+	}
+
+	testdata := []testrow{
+		{[]string{"import", "-", path("a.bga")}, "5\n300\n7\n1\n300\n", ""},
+		{[]string{"info", path("a.bga")}, "", "format:     bigarray\nversion:    1\nlength:     5\nbpv:        2\nmax:        300\n"},
+		{[]string{"dump", "-range", "1:3", "-index", path("a.bga")}, "", "1\t300\n2\t7\n"},
+		{[]string{"verify", path("a.bga")}, "", path("a.bga") + ": OK\n"},
+		{[]string{"stats", "-buckets", "2", path("a.bga")}, "", "count: 5\nmin:   1\nmax:   300\nsum:   613\nmean:  122.600\nhistogram:\n  [1, 150]: 3\n  [151, 300]: 2\n"},
+		{[]string{"sort", path("a.bga"), path("s.bga")}, "", ""},
+		{[]string{"dump", path("s.bga")}, "", "1\n5\n7\n300\n300\n"},
+		{[]string{"info", path("s.bga")}, "", "format:     bigarray\nversion:    1\nlength:     5\nbpv:        2\nmax:        300\n"},
+		{[]string{"convert", "-compact", "-gzip", path("a.bga"), path("c.bga.gz")}, "", ""},
+		{[]string{"info", path("c.bga.gz")}, "", "format:     bigarray\nversion:    1\ncompressed: gzip\nlength:     5\nbpv:        2\nmax:        300\n"},
+		{[]string{"export", "-format", "npy", path("c.bga.gz"), path("a.npy")}, "", ""},
+		{[]string{"info", path("a.npy")}, "", "format:     npy\nversion:    1.0\nlength:     5\nbpv:        2\nmax:        65535\n"},
+		{[]string{"export", "-format", "arrow", path("a.npy"), path("a.arrow")}, "", ""},
+		{[]string{"import", "-format", "arrow", path("a.arrow"), path("b.bga")}, "", ""},
+		{[]string{"export", "-hex", "-index", "-delim", ";", path("b.bga"), "-"}, "", "0;0x5\n1;0x12c\n2;0x7\n3;0x1\n4;0x12c\n"},
+	}
+
+	for _, row := range testdata {
+		actual, code := runCommand(t, row.stdin, row.args...)
+		if code != 0 {
+			t.Errorf("%v: exit %d: %s", row.args, code, actual)
+			continue
+		}
+		if actual != row.expect {
+			t.Errorf("%v: expected %q, got %q", row.args, row.expect, actual)
+		}
+	}
+
+	raw, err := ioutil.ReadFile(path("a.bga"))
+	if err != nil {
+		t.Fatalf("ReadFile: error: %v", err)
+	}
+	raw[len(raw)-5] ^= 1
+	if err := ioutil.WriteFile(path("bad.bga"), raw, 0666); err != nil {
+		t.Fatalf("WriteFile: error: %v", err)
+	}
+	if _, code := runCommand(t, "", "verify", path("bad.bga")); code != 1 {
+		t.Errorf("verify: expected exit 1 for bad checksum, got %d", code)
+	}
+	npy, err := ioutil.ReadFile(path("a.npy"))
+	if err != nil {
+		t.Fatalf("ReadFile: error: %v", err)
+	}
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(npy[0:len(npy)-4])
+	zw.Close()
+	if err := ioutil.WriteFile(path("a.npy.gz"), gz.Bytes(), 0666); err != nil {
+		t.Fatalf("WriteFile: error: %v", err)
+	}
+	const expectInfo = "format:     npy\nversion:    1.0\ncompressed: gzip\nlength:     5\nbpv:        2\nmax:        65535\n"
+	if actual, code := runCommand(t, "", "info", path("a.npy.gz")); code != 0 || actual != expectInfo {
+		t.Errorf("info: expected %q from the header alone, got exit %d: %q", expectInfo, code, actual)
+	}
+
+	var stderr bytes.Buffer
+	e := &env{stdin: strings.NewReader(""), stdout: failWriter{}, stderr: &stderr}
+	if code := run(e, []string{"dump", path("a.bga")}); code != 1 {
+		t.Errorf("dump: expected exit 1 for a failed write, got %d", code)
+	}
+
+	if _, code := runCommand(t, "", "dump"); code != 2 {
+		t.Errorf("dump: expected exit 2 for missing argument, got %d", code)
+	}
+	if _, code := runCommand(t, "", "frobnicate"); code != 2 {
+		t.Errorf("frobnicate: expected exit 2 for unknown command, got %d", code)
+	}
+}
+
+func TestCommands_Nullable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bigarray")
+	if err != nil {
+		t.Fatalf("TempDir: error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	ba, err := bigarray.New(bigarray.MaxValue(300), bigarray.NumValues(5), bigarray.Nullable())
+	if err != nil {
+		t.Fatalf("New: error: %v", err)
+	}
+	defer ba.Close()
+	ba.SetValueAt(0, 300)
+	ba.SetValueAt(2, 7)
+	ba.SetValueAt(4, 0)
+	var buf bytes.Buffer
+	if err := bigarray.WriteArrowIPC(&buf, "values", ba); err != nil {
+		t.Fatalf("WriteArrowIPC: error: %v", err)
+	}
+	if err := ioutil.WriteFile(path("n.arrow"), buf.Bytes(), 0666); err != nil {
+		t.Fatalf("WriteFile: error: %v", err)
+	}
+
+	type testrow struct {
+		args   []string
+		expect string
+	}
+
+	testdata := []testrow{
+		{[]string{"import", "-format", "arrow", path("n.arrow"), path("n.bga")}, ""},
+		{[]string{"dump", "-index", path("n.bga")}, "0\t300\n1\tnull\n2\t7\n3\tnull\n4\t0\n"},
+		{[]string{"sort", path("n.bga"), path("s.bga")}, ""},
+		{[]string{"dump", path("s.bga")}, "0\n7\n300\nnull\nnull\n"},
+		{[]string{"export", "-format", "arrow", path("s.bga"), path("s.arrow")}, ""},
+		{[]string{"import", "-format", "arrow", path("s.arrow"), path("t.bga")}, ""},
+		{[]string{"dump", "-hex", path("t.bga")}, "0x0\n0x7\n0x12c\nnull\nnull\n"},
+	}
+
+	for _, row := range testdata {
+		actual, code := runCommand(t, "", row.args...)
+		if code != 0 {
+			t.Errorf("%v: exit %d: %s", row.args, code, actual)
+			continue
+		}
+		if actual != row.expect {
+			t.Errorf("%v: expected %q, got %q", row.args, row.expect, actual)
+		}
+	}
+
+	for _, format := range []string{"csv", "npy"} {
+		if _, code := runCommand(t, "", "export", "-format", format, path("n.bga"), "-"); code != 1 {
+			t.Errorf("export -format %s: expected exit 1 for nulls, got %d", format, code)
+		}
+	}
+}
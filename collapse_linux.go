@@ -0,0 +1,32 @@
+package bigarray
+
+import (
+	"os"
+	"syscall"
+)
+
+const fallocFlCollapseRange = 0x08
+
+// collapseRange removes up to (n) bytes from the start of the file, rounded
+// down to a whole number of filesystem blocks, and returns the number of
+// bytes removed.  It returns 0 if the file or filesystem doesn't support
+// FALLOC_FL_COLLAPSE_RANGE.
+func collapseRange(file File, n uint64) uint64 {
+	f, ok := file.(*os.File)
+	if !ok {
+		return 0
+	}
+	var st syscall.Stat_t
+	if err := syscall.Fstat(int(f.Fd()), &st); err != nil || st.Blksize <= 0 {
+		return 0
+	}
+	blk := uint64(st.Blksize)
+	n = (n / blk) * blk
+	if n == 0 {
+		return 0
+	}
+	if err := syscall.Fallocate(int(f.Fd()), fallocFlCollapseRange, 0, int64(n)); err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package bigarray
+
+// collapseRange is only supported on Linux.
+func collapseRange(file File, n uint64) uint64 {
+	return 0
+}
@@ -0,0 +1,380 @@
+package bigarray
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Concat returns a view which presents the given arrays, in order, as a
+// single array.  No elements are copied.
+//
+// Reads and writes are routed to the part which holds each index, and
+// Iterators stream seamlessly from one part into the next.  Writes pass
+// through to the parts, so they succeed only where the part is writable and
+// the value fits within the part's own MaxValue().  The view's MaxValue() is
+// the smallest MaxValue() of any part, so that any value which fits the view
+// fits every part.
+//
+// If every part is a NullableArray, so is the view.
+//
+// Truncate, Freeze, and Close affect only the view, never the parts.  The
+// parts must not be closed while the view is still in use.
+//
+func Concat(arrays ...BigArray) BigArray {
+	parts := make([]BigArray, len(arrays))
+	nparts := make([]NullableArray, 0, len(arrays))
+	starts := make([]uint64, len(arrays)+1)
+	var max uint64
+	for k, part := range arrays {
+		parts[k] = part
+		starts[k+1] = starts[k] + part.Len()
+		if k == 0 || part.MaxValue() < max {
+			max = part.MaxValue()
+		}
+		if nba, ok := part.(NullableArray); ok {
+			nparts = append(nparts, nba)
+		}
+	}
+	view := concatArray{
+		parts:  parts,
+		starts: starts,
+		num:    starts[len(parts)],
+		max:    max,
+	}
+	if len(parts) != 0 && len(nparts) == len(parts) {
+		return &nullableConcatArray{concatArray: view, nparts: nparts}
+	}
+	return &view
+}
+
+type concatArray struct {
+	parts  []BigArray
+	starts []uint64
+	num    uint64
+	max    uint64
+	ro     bool
+}
+
+// find returns the part which holds the given index.
+func (view *concatArray) find(index uint64) int {
+	return sort.Search(len(view.parts), func(k int) bool {
+		return view.starts[k+1] > index
+	})
+}
+
+func (view *concatArray) Frozen() bool {
+	if view.ro {
+		return true
+	}
+	for _, part := range view.parts {
+		if part.Frozen() {
+			return true
+		}
+	}
+	return false
+}
+
+func (view *concatArray) MaxValue() uint64 {
+	return view.max
+}
+
+func (view *concatArray) Len() uint64 {
+	return view.num
+}
+
+func (view *concatArray) ValueAt(index uint64) (uint64, error) {
+	if index >= view.num {
+		return ^uint64(0), io.EOF
+	}
+	k := view.find(index)
+	return view.parts[k].ValueAt(index - view.starts[k])
+}
+
+func (view *concatArray) SetValueAt(index uint64, value uint64) error {
+	if view.ro {
+		panic("BigArray is read-only")
+	}
+	if index >= view.num {
+		return io.EOF
+	}
+	k := view.find(index)
+	return view.parts[k].SetValueAt(index-view.starts[k], value)
+}
+
+func (view *concatArray) Iterate(i, j uint64) Iterator {
+	if i > j {
+		panic(fmt.Errorf("concatArray.Iterate: i > j: i=%d j=%d", i, j))
+	}
+	return &concatIterator{
+		view: view,
+		base: i,
+		num:  (j - i),
+		part: -1,
+	}
+}
+
+func (view *concatArray) ReverseIterate(i, j uint64) Iterator {
+	if i > j {
+		panic(fmt.Errorf("concatArray.ReverseIterate: i > j: i=%d j=%d", i, j))
+	}
+	return &concatIterator{
+		view: view,
+		base: i,
+		num:  (j - i),
+		part: -1,
+		down: true,
+	}
+}
+
+func (view *concatArray) CopyFrom(src BigArray) error {
+	if view.ro {
+		panic("BigArray is read-only")
+	}
+	if src.Len() != view.Len() {
+		panic("big arrays are not equal in size")
+	}
+	return copyFromImpl(view, src)
+}
+
+// Truncate shrinks the view.  The parts are unaffected.
+func (view *concatArray) Truncate(n uint64) error {
+	if view.ro {
+		panic("BigArray is read-only")
+	}
+	if n > view.num {
+		panic("cannot grow a big array")
+	}
+	view.num = n
+	return nil
+}
+
+// Freeze makes the view read-only.  The parts are unaffected.
+func (view *concatArray) Freeze() error {
+	view.ro = true
+	return view.Flush()
+}
+
+func (view *concatArray) Flush() error {
+	var finalError error
+	for _, part := range view.parts {
+		if err := part.Flush(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	return finalError
+}
+
+func (view *concatArray) Sync() error {
+	var finalError error
+	for _, part := range view.parts {
+		if err := part.Sync(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	return finalError
+}
+
+// Close flushes any writes made through the view.  The parts remain open.
+func (view *concatArray) Close() error {
+	return view.Flush()
+}
+
+func (view *concatArray) Debug() string {
+	return debugImpl(view)
+}
+
+var _ BigArray = (*concatArray)(nil)
+
+type nullableConcatArray struct {
+	concatArray
+	nparts []NullableArray
+}
+
+func (view *nullableConcatArray) IsNull(index uint64) (bool, error) {
+	if index >= view.num {
+		return true, io.EOF
+	}
+	k := view.find(index)
+	return view.nparts[k].IsNull(index - view.starts[k])
+}
+
+func (view *nullableConcatArray) SetNull(index uint64) error {
+	if view.ro {
+		panic("BigArray is read-only")
+	}
+	if index >= view.num {
+		return io.EOF
+	}
+	k := view.find(index)
+	return view.nparts[k].SetNull(index - view.starts[k])
+}
+
+func (view *nullableConcatArray) Debug() string {
+	return debugImpl(view)
+}
+
+var _ NullableArray = (*nullableConcatArray)(nil)
+
+// concatIterator walks the view by holding an Iterator over one part at a
+// time, moving on to the adjacent part when the current one is exhausted.
+type concatIterator struct {
+	view   *concatArray
+	iter   Iterator
+	err    error
+	part   int
+	cur    uint64
+	base   uint64
+	pos    uint64
+	num    uint64
+	primed bool
+	down   bool
+}
+
+func (iter *concatIterator) Err() error { return iter.err }
+func (iter *concatIterator) Next() bool { return iter.Skip(1) }
+
+func (iter *concatIterator) Index() uint64 {
+	if !iter.primed {
+		panic(fmt.Errorf("must call Next() before Index()"))
+	}
+	if iter.pos >= iter.num {
+		panic(fmt.Errorf("must not call Index() after Next() returns false"))
+	}
+	if iter.down {
+		return iter.base + (iter.num - iter.pos - 1)
+	}
+	return iter.base + iter.pos
+}
+
+func (iter *concatIterator) Value() uint64 {
+	iter.Index()
+	return iter.iter.Value()
+}
+
+func (iter *concatIterator) Valid() bool {
+	iter.Index()
+	return iter.iter.Valid()
+}
+
+func (iter *concatIterator) SetValue(value uint64) {
+	iter.Index()
+	if iter.view.ro {
+		panic("BigArray is read-only")
+	}
+	iter.iter.SetValue(value)
+}
+
+func (iter *concatIterator) setNull() {
+	iter.Index()
+	if iter.view.ro {
+		panic("BigArray is read-only")
+	}
+	iter.iter.(nullSetter).setNull()
+}
+
+func (iter *concatIterator) Skip(n uint64) bool {
+	if iter.pos > iter.num {
+		panic(fmt.Sprintf("iter.pos=%d iter.num=%d", iter.pos, iter.num))
+	}
+	if n == 0 && !iter.primed {
+		panic(fmt.Errorf("must call Next() before Skip(0)"))
+	}
+	if iter.err != nil {
+		return false
+	}
+	if !iter.primed {
+		n--
+		iter.primed = true
+	}
+	if n >= (iter.num - iter.pos) {
+		iter.pos = iter.num
+		iter.release()
+		return false
+	}
+	iter.pos += n
+
+	index := iter.Index()
+	if index >= iter.view.num {
+		iter.err = io.EOF
+		iter.release()
+		return false
+	}
+
+	k := iter.view.find(index)
+	if iter.iter != nil && k == iter.part {
+		delta := index - iter.cur
+		if iter.down {
+			delta = iter.cur - index
+		}
+		if delta > 0 && !iter.iter.Skip(delta) {
+			return iter.fail()
+		}
+		iter.cur = index
+		return true
+	}
+
+	iter.release()
+	if iter.err != nil {
+		return false
+	}
+
+	start := iter.view.starts[k]
+	if iter.down {
+		lo := start
+		if iter.base > lo {
+			lo = iter.base
+		}
+		iter.iter = iter.view.parts[k].ReverseIterate(lo-start, index-start+1)
+	} else {
+		hi := iter.view.starts[k+1]
+		if iter.base+iter.num < hi {
+			hi = iter.base + iter.num
+		}
+		iter.iter = iter.view.parts[k].Iterate(index-start, hi-start)
+	}
+	iter.part = k
+	iter.cur = index
+	if !iter.iter.Next() {
+		return iter.fail()
+	}
+	return true
+}
+
+func (iter *concatIterator) fail() bool {
+	iter.err = iter.iter.Err()
+	if iter.err == nil {
+		iter.err = io.ErrUnexpectedEOF
+	}
+	iter.release()
+	return false
+}
+
+// release closes the Iterator over the current part, if any.
+func (iter *concatIterator) release() {
+	if iter.iter == nil {
+		return
+	}
+	if err := iter.iter.Close(); err != nil && iter.err == nil {
+		iter.err = err
+	}
+	iter.iter = nil
+	iter.part = -1
+}
+
+func (iter *concatIterator) Flush() error {
+	if iter.iter == nil {
+		return nil
+	}
+	return iter.iter.Flush()
+}
+
+func (iter *concatIterator) Close() error {
+	iter.release()
+	err := iter.err
+	*iter = concatIterator{err: ErrClosedIterator}
+	return err
+}
+
+var _ Iterator = (*concatIterator)(nil)
+var _ nullSetter = (*concatIterator)(nil)
@@ -0,0 +1,114 @@
+package bigarray
+
+import (
+	"testing"
+)
+
+func RunConcatTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	var parts []BigArray
+	n := uint64(0)
+	for _, size := range []uint64{7, 0, 12, 1, 9} {
+		part, err := New(append(opts, MaxValue(100), PageSize(4), NumValues(size))...)
+		if err != nil {
+			t.Errorf("New: error: %v", err)
+			return
+		}
+		defer part.Close()
+		for i := uint64(0); i < size; i++ {
+			part.SetValueAt(i, n)
+			n++
+		}
+		parts = append(parts, part)
+	}
+
+	view := Concat(parts...)
+	if view.Len() != n {
+		t.Errorf("Concat.Len: expected %d, got %d", n, view.Len())
+	}
+	for i := uint64(0); i < n; i++ {
+		if value, err := view.ValueAt(i); err != nil || value != i {
+			t.Errorf("Concat.ValueAt %d: expected %d, got %d, %v", i, i, value, err)
+		}
+	}
+
+	iter := view.Iterate(3, 25)
+	expect := uint64(3)
+	for iter.Next() {
+		if iter.Index() != expect || iter.Value() != expect {
+			t.Errorf("Concat.Iterate: expected [%d]=%d, got [%d]=%d", expect, expect, iter.Index(), iter.Value())
+		}
+		iter.SetValue(iter.Value() + 50)
+		expect++
+		if expect == 10 && !iter.Skip(5) {
+			t.Errorf("Concat.Iterate: Skip: error: %v", iter.Err())
+		}
+		if expect == 10 {
+			expect = 15
+		}
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("Concat.Iterate: error: %v", err)
+	}
+	if expect != 25 {
+		t.Errorf("Concat.Iterate stopped early at %d", expect)
+	}
+
+	rev := Reversed(view)
+	iter = rev.Iterate(0, rev.Len())
+	expect = 0
+	for iter.Next() {
+		value := n - 1 - expect
+		if (value >= 3 && value < 10) || (value >= 15 && value < 25) {
+			value += 50
+		}
+		if iter.Index() != expect || iter.Value() != value {
+			t.Errorf("Reversed.Iterate: expected [%d]=%d, got [%d]=%d", expect, value, iter.Index(), iter.Value())
+		}
+		expect++
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("Reversed.Iterate: error: %v", err)
+	}
+	if expect != n {
+		t.Errorf("Reversed.Iterate stopped early at %d", expect)
+	}
+
+	const expectDebug = "[28 27 26 25 74 73 72 71 70 69 68 67 66 65 14 13 12 11 10 59 58 57 56 55 54 53 2 1 0]"
+	if actual := rev.Debug(); actual != expectDebug {
+		t.Errorf("Reversed.Debug: expected %s, got %s", expectDebug, actual)
+	}
+	if actual := Reversed(rev).Debug(); actual != view.Debug() {
+		t.Errorf("Reversed twice: expected %s, got %s", view.Debug(), actual)
+	}
+}
+
+func TestConcat_InMemory(t *testing.T) {
+	RunConcatTests(t)
+}
+
+func TestConcat_OnDisk(t *testing.T) {
+	RunConcatTests(t, OnDiskThreshold(0))
+}
+
+func TestConcat_MaxValue(t *testing.T) {
+	a, err := New(MaxValue(255), NumValues(4))
+	if err != nil {
+		t.Fatalf("New: error: %v", err)
+	}
+	defer a.Close()
+	b, err := New(MaxValue(65535), NumValues(4))
+	if err != nil {
+		t.Fatalf("New: error: %v", err)
+	}
+	defer b.Close()
+
+	view := Concat(a, b)
+	if max := view.MaxValue(); max != 255 {
+		t.Errorf("Concat.MaxValue: expected 255, got %d", max)
+	}
+	if max := Concat(b, a).MaxValue(); max != 255 {
+		t.Errorf("Concat.MaxValue: expected 255, got %d", max)
+	}
+}
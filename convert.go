@@ -0,0 +1,115 @@
+package bigarray
+
+import (
+	"fmt"
+)
+
+// OutOfRangeError is returned when a value cannot be stored in an array
+// because it exceeds the array's MaxValue().
+type OutOfRangeError struct {
+	Index uint64
+	Value uint64
+	Max   uint64
+}
+
+func (err *OutOfRangeError) Error() string {
+	return fmt.Sprintf("value out of range at index %d: value %d vs max %d", err.Index, err.Value, err.Max)
+}
+
+// Convert constructs a new array with the same length and contents as (src),
+// but with the storage customized by the provided options.  This is typically
+// used with BytesPerValue to change the width of an array.
+//
+// NumValues is taken from (src).  If MaxValue is not specified, it defaults to
+// src.MaxValue(), capped to the largest value representable by BytesPerValue.
+// If narrowing the array would lose information, an *OutOfRangeError is
+// returned.
+//
+// Values are streamed between Iterators, so on-disk arrays are processed one
+// page at a time.  Whether the new array is in-memory or on-disk is decided
+// by OnDiskThreshold, as for New.
+//
+func Convert(src BigArray, opts ...Option) (BigArray, error) {
+	var o options
+	o.apply(opts...)
+	if o.maxValue == 0 {
+		o.maxValue = src.MaxValue()
+		if o.bytesPerValue != 0 && o.maxValue > calcBPVToMax(o.bytesPerValue) {
+			o.maxValue = calcBPVToMax(o.bytesPerValue)
+		}
+	}
+	o.numValues = src.Len()
+	o.populate()
+
+	dst, err := build(o)
+	if err != nil {
+		return nil, err
+	}
+	if err := convertImpl(dst, src); err != nil {
+		dst.Close()
+		return nil, err
+	}
+	return dst, nil
+}
+
+// Compact constructs a new array with the same length and contents as (src),
+// using the smallest BytesPerValue which can hold src's largest element.
+//
+// The options are interpreted as for Convert.  Compact makes two passes over
+// (src): one to find the largest element, and one to copy the elements.
+//
+func Compact(src BigArray, opts ...Option) (BigArray, error) {
+	var max uint64
+	err := ForEach(src, func(_ uint64, value uint64) error {
+		if value > max {
+			max = value
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append([]Option{BytesPerValue(calcMaxToBPV(max))}, opts...)
+	return Convert(src, opts...)
+}
+
+func convertImpl(dst, src BigArray) error {
+	if src.MaxValue() <= dst.MaxValue() {
+		return dst.CopyFrom(src)
+	}
+
+	srcIter := src.Iterate(0, src.Len())
+	needCloseSrc := true
+	defer func() {
+		if needCloseSrc {
+			srcIter.Close()
+		}
+	}()
+
+	dstIter := dst.Iterate(0, dst.Len())
+	needCloseDst := true
+	defer func() {
+		if needCloseDst {
+			dstIter.Close()
+		}
+	}()
+
+	max := dst.MaxValue()
+	for srcIter.Next() && dstIter.Next() {
+		value := srcIter.Value()
+		if value > max {
+			return &OutOfRangeError{Index: srcIter.Index(), Value: value, Max: max}
+		}
+		dstIter.SetValue(value)
+	}
+
+	needCloseDst = false
+	err := dstIter.Close()
+	if err != nil {
+		return err
+	}
+
+	needCloseSrc = false
+	return srcIter.Close()
+}
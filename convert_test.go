@@ -0,0 +1,60 @@
+package bigarray
+
+import (
+	"testing"
+)
+
+func RunConvertTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	src, err := New(append(opts, BytesPerValue(8), PageSize(16), NumValues(50))...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer src.Close()
+
+	for i := uint64(0); i < src.Len(); i++ {
+		src.SetValueAt(i, i*5)
+	}
+
+	compact, err := Compact(src, opts...)
+	if err != nil {
+		t.Errorf("Compact: error: %v", err)
+		return
+	}
+	defer compact.Close()
+	if compact.MaxValue() != 0xff {
+		t.Errorf("Compact: expected MaxValue 255, got %d", compact.MaxValue())
+	}
+	if actual, expect := compact.Debug(), src.Debug(); actual != expect {
+		t.Errorf("Compact: expected %s, got %s", expect, actual)
+	}
+
+	wide, err := Convert(compact, append(opts, BytesPerValue(4))...)
+	if err != nil {
+		t.Errorf("Convert: error: %v", err)
+		return
+	}
+	defer wide.Close()
+	if wide.MaxValue() != 0xff {
+		t.Errorf("Convert: expected MaxValue 255, got %d", wide.MaxValue())
+	}
+	if actual, expect := wide.Debug(), src.Debug(); actual != expect {
+		t.Errorf("Convert: expected %s, got %s", expect, actual)
+	}
+
+	src.SetValueAt(7, 300)
+	_, err = Convert(src, append(opts, BytesPerValue(1))...)
+	if x, ok := err.(*OutOfRangeError); !ok || x.Index != 7 || x.Value != 300 {
+		t.Errorf("Convert: expected OutOfRangeError at index 7, got %v", err)
+	}
+}
+
+func TestConvert_InMemory(t *testing.T) {
+	RunConvertTests(t)
+}
+
+func TestConvert_OnDisk(t *testing.T) {
+	RunConvertTests(t, OnDiskThreshold(0))
+}
@@ -0,0 +1,243 @@
+package bigarray
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// copyChunkSize is the number of values which the CopyFrom fast paths decode
+// at a time.
+const copyChunkSize = 4096
+
+// copyFromFast implements CopyFrom for pairs of in-memory and on-disk arrays
+// without going through Iterators.  It returns false if the generic
+// copyFromImpl must be used instead.
+//
+//   - in-memory to in-memory: widen/narrow loops over the typed slices
+//   - in-memory to on-disk: one WriteAt per chunk of encoded values
+//   - on-disk to in-memory: one ReadAt per chunk of encoded values
+//   - on-disk to on-disk, same width: page-sized blocks of raw bytes
+//   - on-disk to on-disk, different widths: page-sized widen/narrow loops
+//
+// The caller is responsible for checking that the arrays have equal length
+// and that (dst) is writable.
+func copyFromFast(dst, src BigArray) (bool, error) {
+	src, unlock := lockImpl(src)
+	defer unlock()
+	if d, ok := dst.(*onDiskArray); ok && len(d.cache) != 0 {
+		return false, nil
+	}
+
+	num := dst.Len()
+	srcMem := isInMemory(src)
+	dstMem := isInMemory(dst)
+	s, srcDisk := src.(*onDiskArray)
+	d, dstDisk := dst.(*onDiskArray)
+	max := dst.MaxValue()
+	check := src.MaxValue() > max
+
+	switch {
+	case srcMem && dstMem:
+		buf := make([]uint64, chunkSize(num))
+		for off := uint64(0); off < num; off += uint64(len(buf)) {
+			chunk := buf[0:chunkSize(num-off)]
+			memDecode(src, off, chunk)
+			checkValues(chunk, max, check)
+			memEncode(dst, off, chunk)
+		}
+		return true, nil
+
+	case srcMem && dstDisk:
+		bpv := uint64(d.bpv)
+		buf := make([]uint64, chunkSize(num))
+		raw := make([]byte, uint64(len(buf))*bpv)
+		for off := uint64(0); off < num; off += uint64(len(buf)) {
+			chunk := buf[0:chunkSize(num-off)]
+			memDecode(src, off, chunk)
+			checkValues(chunk, max, check)
+			rawEncode(d.bpv, raw, chunk)
+			size := uint64(len(chunk)) * bpv
+			if _, err := d.writeAt(raw[0:size], int64(d.base+off*bpv)); err != nil {
+				return true, err
+			}
+		}
+		return true, nil
+
+	case srcDisk && dstMem:
+		if err := s.Flush(); err != nil {
+			return true, err
+		}
+		bpv := uint64(s.bpv)
+		buf := make([]uint64, chunkSize(num))
+		raw := make([]byte, uint64(len(buf))*bpv)
+		for off := uint64(0); off < num; off += uint64(len(buf)) {
+			chunk := buf[0:chunkSize(num-off)]
+			size := uint64(len(chunk)) * bpv
+			if _, err := s.readAt(raw[0:size], int64(s.base+off*bpv)); err != nil {
+				return true, err
+			}
+			rawDecode(s.bpv, raw, chunk)
+			checkValues(chunk, max, check)
+			memEncode(dst, off, chunk)
+		}
+		return true, nil
+
+	case srcDisk && dstDisk && s.bpv == d.bpv && !check:
+		return true, copyBytesOnDisk(d, 0, s, 0, num, false)
+
+	case srcDisk && dstDisk:
+		if err := s.Flush(); err != nil {
+			return true, err
+		}
+		sbpv := uint64(s.bpv)
+		dbpv := uint64(d.bpv)
+		step := uint64(s.psz) / sbpv
+		buf := make([]uint64, step)
+		sraw := make([]byte, step*sbpv)
+		draw := make([]byte, step*dbpv)
+		for off := uint64(0); off < num; off += step {
+			n := num - off
+			if n > step {
+				n = step
+			}
+			if _, err := s.readAt(sraw[0:n*sbpv], int64(s.base+off*sbpv)); err != nil {
+				return true, err
+			}
+			rawDecode(s.bpv, sraw, buf[0:n])
+			checkValues(buf[0:n], max, check)
+			rawEncode(d.bpv, draw, buf[0:n])
+			if _, err := d.writeAt(draw[0:n*dbpv], int64(d.base+off*dbpv)); err != nil {
+				return true, err
+			}
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func chunkSize(n uint64) uint64 {
+	if n > copyChunkSize {
+		return copyChunkSize
+	}
+	return n
+}
+
+func isInMemory(ba BigArray) bool {
+	switch ba.(type) {
+	case *inMemoryArray8, *inMemoryArray16, *inMemoryArray32, *inMemoryArray64:
+		return true
+	default:
+		return false
+	}
+}
+
+func checkValues(buf []uint64, max uint64, check bool) {
+	if !check {
+		return
+	}
+	for _, value := range buf {
+		if value > max {
+			panic(fmt.Sprintf("value out of range: value %d vs max %d", value, max))
+		}
+	}
+}
+
+// memDecode copies the values of an in-memory array, starting at index (off),
+// into (buf).
+func memDecode(ba BigArray, off uint64, buf []uint64) {
+	end := off + uint64(len(buf))
+	switch x := ba.(type) {
+	case *inMemoryArray8:
+		for i, v := range x.data[off:end] {
+			buf[i] = uint64(v)
+		}
+	case *inMemoryArray16:
+		for i, v := range x.data[off:end] {
+			buf[i] = uint64(v)
+		}
+	case *inMemoryArray32:
+		for i, v := range x.data[off:end] {
+			buf[i] = uint64(v)
+		}
+	case *inMemoryArray64:
+		copy(buf, x.data[off:end])
+	default:
+		panic("BUG")
+	}
+}
+
+// memEncode copies the values in (buf) into an in-memory array, starting at
+// index (off).
+func memEncode(ba BigArray, off uint64, buf []uint64) {
+	end := off + uint64(len(buf))
+	switch x := ba.(type) {
+	case *inMemoryArray8:
+		data := x.data[off:end]
+		for i, v := range buf {
+			data[i] = byte(v)
+		}
+	case *inMemoryArray16:
+		data := x.data[off:end]
+		for i, v := range buf {
+			data[i] = uint16(v)
+		}
+	case *inMemoryArray32:
+		data := x.data[off:end]
+		for i, v := range buf {
+			data[i] = uint32(v)
+		}
+	case *inMemoryArray64:
+		copy(x.data[off:end], buf)
+	default:
+		panic("BUG")
+	}
+}
+
+// rawDecode decodes len(buf) little-endian values of (bpv) bytes each.
+func rawDecode(bpv byte, raw []byte, buf []uint64) {
+	switch bpv {
+	case 1:
+		for i := range buf {
+			buf[i] = uint64(raw[i])
+		}
+	case 2:
+		for i := range buf {
+			buf[i] = uint64(binary.LittleEndian.Uint16(raw[2*i:]))
+		}
+	case 4:
+		for i := range buf {
+			buf[i] = uint64(binary.LittleEndian.Uint32(raw[4*i:]))
+		}
+	case 8:
+		for i := range buf {
+			buf[i] = binary.LittleEndian.Uint64(raw[8*i:])
+		}
+	default:
+		panic("BUG")
+	}
+}
+
+// rawEncode encodes the values in (buf) as little-endian values of (bpv)
+// bytes each.
+func rawEncode(bpv byte, raw []byte, buf []uint64) {
+	switch bpv {
+	case 1:
+		for i, v := range buf {
+			raw[i] = byte(v)
+		}
+	case 2:
+		for i, v := range buf {
+			binary.LittleEndian.PutUint16(raw[2*i:], uint16(v))
+		}
+	case 4:
+		for i, v := range buf {
+			binary.LittleEndian.PutUint32(raw[4*i:], uint32(v))
+		}
+	case 8:
+		for i, v := range buf {
+			binary.LittleEndian.PutUint64(raw[8*i:], v)
+		}
+	default:
+		panic("BUG")
+	}
+}
@@ -0,0 +1,47 @@
+package bigarray
+
+import (
+	"testing"
+)
+
+func TestCopyFrom_FastPaths(t *testing.T) {
+	type config struct {
+		name string
+		opts []Option
+	}
+	configs := []config{
+		{"mem8", []Option{BytesPerValue(1)}},
+		{"mem32", []Option{BytesPerValue(4)}},
+		{"disk8", []Option{BytesPerValue(1), OnDiskThreshold(0)}},
+		{"disk16", []Option{BytesPerValue(2), OnDiskThreshold(0)}},
+		{"disk64", []Option{BytesPerValue(8), OnDiskThreshold(0)}},
+	}
+
+	// Enough values for the fast paths to take more than one chunk.
+	const num = copyChunkSize + 100
+
+	for _, sc := range configs {
+		for _, dc := range configs {
+			src, err := New(append(sc.opts, PageSize(16), NumValues(num))...)
+			if err != nil {
+				t.Fatalf("New: error: %v", err)
+			}
+			for i := uint64(0); i < src.Len(); i++ {
+				src.SetValueAt(i, (i*37)%251)
+			}
+
+			dst, err := New(append(dc.opts, PageSize(16), NumValues(num))...)
+			if err != nil {
+				t.Fatalf("New: error: %v", err)
+			}
+			if err := dst.CopyFrom(src); err != nil {
+				t.Errorf("%s -> %s: CopyFrom: error: %v", sc.name, dc.name, err)
+			}
+			if actual, expect := dst.Debug(), src.Debug(); actual != expect {
+				t.Errorf("%s -> %s: CopyFrom: expected %s, got %s", sc.name, dc.name, expect, actual)
+			}
+			dst.Close()
+			src.Close()
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package bigarray
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultSyncInterval is the default for SyncInterval.
+const defaultSyncInterval = time.Second
+
+// DurabilityPolicy selects when an on-disk array asks the OS to commit its
+// writes to stable storage.  See Durability.
+type DurabilityPolicy uint8
+
+const (
+	// SyncNever leaves syncing to the caller, who may call Sync.  This is
+	// the default.
+	SyncNever DurabilityPolicy = iota
+
+	// SyncOnFlush syncs the file after every Flush, and on Close.
+	SyncOnFlush
+
+	// SyncOnClose syncs the file on Close.
+	SyncOnClose
+
+	// SyncPeriodically syncs the file from a background goroutine once
+	// every SyncInterval, and on Close.  Only writes which have reached the
+	// OS, i.e. which have been flushed, are covered by a periodic sync.
+	// The goroutine runs until Close, and calls the File's Sync method
+	// concurrently with its other methods, which must allow that.
+	SyncPeriodically
+)
+
+var durabilityNames = []string{
+	"SyncNever",
+	"SyncOnFlush",
+	"SyncOnClose",
+	"SyncPeriodically",
+}
+
+func (policy DurabilityPolicy) String() string {
+	if int(policy) < len(durabilityNames) {
+		return durabilityNames[policy]
+	}
+	return fmt.Sprintf("DurabilityPolicy(%d)", uint8(policy))
+}
+
+// durability applies a DurabilityPolicy to the backing file of an on-disk
+// array.  It is shared with any array which replaces it, e.g. when an
+// AutoWiden array is widened.
+type durability struct {
+	f      File
+	policy DurabilityPolicy
+	stop   chan struct{}
+	done   chan struct{}
+	mu     sync.Mutex
+	err    error
+}
+
+// newDurability returns the durability for an on-disk array, or nil if the
+// policy is SyncNever.  Temporary files do not outlive the process, and
+// read-only files are never written, so neither is ever synced.
+func newDurability(o options, doc bool) *durability {
+	if o.durability == SyncNever || doc || o.isReadOnly {
+		return nil
+	}
+	d := &durability{f: o.backingFile, policy: o.durability}
+	if d.policy == SyncPeriodically {
+		interval := o.syncInterval
+		if interval <= 0 {
+			interval = defaultSyncInterval
+		}
+		d.stop = make(chan struct{})
+		d.done = make(chan struct{})
+		go d.loop(interval)
+	}
+	return d
+}
+
+func (d *durability) loop(interval time.Duration) {
+	defer close(d.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			if err := syncFile(d.f); err != nil {
+				d.setErr(err)
+			}
+		}
+	}
+}
+
+func (d *durability) setErr(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+// takeErr returns and clears the first error from a background sync.
+func (d *durability) takeErr() error {
+	if d == nil || d.stop == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	err := d.err
+	d.err = nil
+	return err
+}
+
+// afterFlush is called after each successful Flush.
+func (d *durability) afterFlush() error {
+	if d == nil || d.policy != SyncOnFlush {
+		return nil
+	}
+	return syncFile(d.f)
+}
+
+// beforeClose stops the background goroutine, if any, and syncs the file
+// one last time.  An error from an earlier background sync takes priority.
+func (d *durability) beforeClose() error {
+	if d == nil {
+		return nil
+	}
+	if d.stop != nil {
+		close(d.stop)
+		<-d.done
+	}
+	err := syncFile(d.f)
+	if bgErr := d.takeErr(); bgErr != nil {
+		err = bgErr
+	}
+	return err
+}
+
+// syncFile asks the OS to commit the writes to (f) to stable storage.
+// Files from WithReadOnlyFile cannot be written, so there is nothing to
+// sync; other files must have a Sync method.
+func syncFile(f File) error {
+	type syncer interface{ Sync() error }
+
+	switch x := f.(type) {
+	case *os.File:
+		return fdatasync(x)
+	case wrappedReaderAt:
+		return nil
+	case syncer:
+		return x.Sync()
+	default:
+		return &NotImplementedError{Op: "Sync"}
+	}
+}
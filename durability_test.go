@@ -0,0 +1,193 @@
+package bigarray
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memFile is an in-memory File which counts calls to Sync.
+type memFile struct {
+	mu    sync.Mutex
+	data  []byte
+	syncs int
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return bytes.NewReader(f.data).ReadAt(p, off)
+}
+
+func (f *memFile) WriteAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if end := int(off) + len(p); end > len(f.data) {
+		f.data = append(f.data, make([]byte, end-len(f.data))...)
+	}
+	return copy(f.data[off:], p), nil
+}
+
+func (f *memFile) Truncate(n int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if int(n) > len(f.data) {
+		f.data = append(f.data, make([]byte, int(n)-len(f.data))...)
+	}
+	f.data = f.data[0:n]
+	return nil
+}
+
+func (f *memFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.syncs++
+	return nil
+}
+
+func (f *memFile) Close() error { return nil }
+
+func (f *memFile) syncCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.syncs
+}
+
+func TestDurability(t *testing.T) {
+	type testrow struct {
+		policy      DurabilityPolicy
+		afterFlush  int
+		afterClose  int
+		description string
+	}
+	for _, row := range []testrow{
+		{SyncNever, 0, 0, "SyncNever"},
+		{SyncOnFlush, 1, 2, "SyncOnFlush"},
+		{SyncOnClose, 0, 1, "SyncOnClose"},
+	} {
+		f := &memFile{}
+		ba, err := New(MaxValue(255), NumValues(16), WithFile(f), Durability(row.policy))
+		if err != nil {
+			t.Errorf("%s: New: error: %v", row.description, err)
+			continue
+		}
+		ba.SetValueAt(3, 7)
+		if err := ba.Flush(); err != nil {
+			t.Errorf("%s: BigArray.Flush: error: %v", row.description, err)
+		}
+		if n := f.syncCount(); n != row.afterFlush {
+			t.Errorf("%s: after Flush: expected %d syncs, got %d", row.description, row.afterFlush, n)
+		}
+		if err := ba.Close(); err != nil {
+			t.Errorf("%s: BigArray.Close: error: %v", row.description, err)
+		}
+		if n := f.syncCount(); n != row.afterClose {
+			t.Errorf("%s: after Close: expected %d syncs, got %d", row.description, row.afterClose, n)
+		}
+	}
+}
+
+func TestDurability_Periodic(t *testing.T) {
+	f := &memFile{}
+	ba, err := New(
+		MaxValue(255),
+		NumValues(16),
+		WithFile(f),
+		Durability(SyncPeriodically),
+		SyncInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: error: %v", err)
+	}
+
+	// The background goroutine syncs without waiting for a Flush.
+	deadline := time.Now().Add(5 * time.Second)
+	for f.syncCount() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := f.syncCount(); n < 2 {
+		t.Errorf("SyncPeriodically: expected repeated syncs within 5s, got %d", n)
+	}
+
+	if err := ba.Close(); err != nil {
+		t.Errorf("BigArray.Close: error: %v", err)
+	}
+	n := f.syncCount()
+	time.Sleep(10 * time.Millisecond)
+	if f.syncCount() != n {
+		t.Errorf("SyncPeriodically: syncs continued after Close")
+	}
+}
+
+func TestDurability_Sync(t *testing.T) {
+	f := &memFile{}
+	ba, err := New(MaxValue(255), NumValues(16), WithFile(f), Durability(SyncOnFlush))
+	if err != nil {
+		t.Fatalf("New: error: %v", err)
+	}
+	defer ba.Close()
+	ba.SetValueAt(3, 7)
+	if err := ba.Sync(); err != nil {
+		t.Errorf("BigArray.Sync: error: %v", err)
+	}
+	if n := f.syncCount(); n != 1 {
+		t.Errorf("SyncOnFlush: expected Sync to sync once, got %d", n)
+	}
+}
+
+func TestSync(t *testing.T) {
+	mem, err := New(MaxValue(255), NumValues(16))
+	if err != nil {
+		t.Fatalf("New: error: %v", err)
+	}
+	defer mem.Close()
+	if err := mem.Sync(); err != nil {
+		t.Errorf("in-memory BigArray.Sync: error: %v", err)
+	}
+
+	tmp, err := New(MaxValue(255), NumValues(16), OnDiskThreshold(0))
+	if err != nil {
+		t.Fatalf("New: error: %v", err)
+	}
+	defer tmp.Close()
+	tmp.SetValueAt(1, 1)
+	if err := Slice(tmp, 0, 8).Sync(); err != nil {
+		t.Errorf("Slice.Sync: error: %v", err)
+	}
+
+	nullable, err := New(MaxValue(255), NumValues(16), OnDiskThreshold(0), Nullable())
+	if err != nil {
+		t.Fatalf("New: error: %v", err)
+	}
+	defer nullable.Close()
+	nullable.SetValueAt(2, 9)
+	if err := nullable.Sync(); err != nil {
+		t.Errorf("nullable BigArray.Sync: error: %v", err)
+	}
+
+	ro, err := New(MaxValue(255), NumValues(4), WithReadOnlyFile(bytes.NewReader(make([]byte, 4))))
+	if err != nil {
+		t.Fatalf("New: error: %v", err)
+	}
+	defer ro.Close()
+	if err := ro.Sync(); err != nil {
+		t.Errorf("WithReadOnlyFile BigArray.Sync: error: %v", err)
+	}
+
+	f, err := ioutil.TempFile("", "sync")
+	if err != nil {
+		t.Fatalf("TempFile: error: %v", err)
+	}
+	defer os.Remove(f.Name())
+	ba, err := New(MaxValue(255), NumValues(16), WithFile(f))
+	if err != nil {
+		t.Fatalf("New: error: %v", err)
+	}
+	defer ba.Close()
+	ba.SetValueAt(2, 9)
+	if err := ba.Sync(); err != nil {
+		t.Errorf("WithFile BigArray.Sync: error: %v", err)
+	}
+}
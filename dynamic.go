@@ -0,0 +1,622 @@
+package bigarray
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// dynamicArray is a stable handle around a BigArray whose representation may
+// be replaced at runtime, e.g. to widen it when a value exceeds MaxValue().
+//
+// Iterators created through the handle are tracked, so that they can be
+// migrated to the new representation whenever it changes.
+//
+// Arrays created with a MemoryBudget are always wrapped in a dynamicArray, so
+// that the budget can move them to disk.  The fields (held), (lastUse) and
+// (spill) belong to the budget; see budget.go.
+//
+// (mu) is a lock held by every method which uses (impl), and by the budget
+// while it moves an idle array to disk from another goroutine.  It is a
+// channel so that the budget can try it without waiting.  The budget leaves
+// alone an array with outstanding iterators, or which is (busy) in a call
+// that has released the lock.
+//
+// (dropped) counts the bytes left in front of an in-memory representation by
+// DropPrefix.  They are still allocated, so they stay charged to the budget
+// until the representation is reallocated.
+type dynamicArray struct {
+	mu      chan struct{}
+	impl    BigArray
+	o       options
+	iters   map[*dynamicIterator]struct{}
+	busy    int
+	closed  bool
+	dropped uint64
+	held    uint64
+	lastUse uint64
+	spill   int32
+}
+
+func newDynamicArray(o options) (BigArray, error) {
+	mb := o.budget
+	implOpts := o
+	var held uint64
+	if mb != nil && o.backingFile == nil {
+		numBytes := o.numValues * uint64(o.bytesPerValue)
+		if numBytes < o.diskThreshold {
+			if mb.reserve(numBytes) {
+				held = numBytes
+			} else {
+				implOpts.diskThreshold = 0
+			}
+		}
+	}
+
+	impl, err := newImpl(implOpts)
+	if err != nil {
+		mb.release(held)
+		return nil, err
+	}
+	ba := &dynamicArray{
+		mu:    make(chan struct{}, 1),
+		impl:  impl,
+		o:     o,
+		iters: make(map[*dynamicIterator]struct{}),
+	}
+	if mb != nil {
+		mb.track(ba, held)
+	}
+	return ba, nil
+}
+
+func (ba *dynamicArray) Frozen() bool {
+	ba.lock()
+	defer ba.unlock()
+	return ba.impl.Frozen()
+}
+
+func (ba *dynamicArray) MaxValue() uint64 {
+	ba.lock()
+	defer ba.unlock()
+	return ba.impl.MaxValue()
+}
+
+func (ba *dynamicArray) Len() uint64 {
+	ba.lock()
+	defer ba.unlock()
+	return ba.impl.Len()
+}
+
+func (ba *dynamicArray) ValueAt(index uint64) (uint64, error) {
+	ba.lock()
+	defer ba.unlock()
+	if err := ba.touch(); err != nil {
+		return ^uint64(0), err
+	}
+	return ba.impl.ValueAt(index)
+}
+
+func (ba *dynamicArray) SetValueAt(index uint64, value uint64) error {
+	ba.lock()
+	defer ba.unlock()
+	if err := ba.touch(); err != nil {
+		return err
+	}
+	if err := ba.ensureFits(value); err != nil {
+		return err
+	}
+	return ba.impl.SetValueAt(index, value)
+}
+
+func (ba *dynamicArray) Iterate(i, j uint64) Iterator {
+	if i > j {
+		panic(fmt.Errorf("dynamicArray.Iterate: i > j: i=%d j=%d", i, j))
+	}
+	ba.lock()
+	defer ba.unlock()
+	// A failed spill leaves the array intact in memory, so it is not fatal.
+	ba.touch()
+	iter := &dynamicIterator{
+		ba:   ba,
+		iter: ba.impl.Iterate(i, j),
+		i:    i,
+		j:    j,
+	}
+	ba.iters[iter] = struct{}{}
+	return iter
+}
+
+func (ba *dynamicArray) ReverseIterate(i, j uint64) Iterator {
+	if i > j {
+		panic(fmt.Errorf("dynamicArray.ReverseIterate: i > j: i=%d j=%d", i, j))
+	}
+	ba.lock()
+	defer ba.unlock()
+	ba.touch()
+	iter := &dynamicIterator{
+		ba:   ba,
+		iter: ba.impl.ReverseIterate(i, j),
+		i:    i,
+		j:    j,
+		down: true,
+	}
+	ba.iters[iter] = struct{}{}
+	return iter
+}
+
+func (ba *dynamicArray) CopyFrom(src BigArray) error {
+	if ba.Frozen() {
+		panic("BigArray is read-only")
+	}
+	if src.Len() != ba.Len() {
+		panic("big arrays are not equal in size")
+	}
+	srcMax := src.MaxValue()
+	ba.lock()
+	err := ba.touch()
+	impl := ba.impl
+	if err != nil || srcMax > impl.MaxValue() {
+		ba.unlock()
+		if err != nil {
+			return err
+		}
+		return copyFromImpl(ba, src)
+	}
+	// (src) may be a view of this array, so the lock cannot be held
+	// while it is read.  Marking the array busy keeps (impl) in place.
+	ba.busy++
+	ba.unlock()
+	err = impl.CopyFrom(src)
+	ba.lock()
+	ba.busy--
+	ba.unlock()
+	return err
+}
+
+func (ba *dynamicArray) Truncate(n uint64) error {
+	ba.lock()
+	defer ba.unlock()
+	if err := ba.impl.Truncate(n); err != nil {
+		return err
+	}
+	return ba.resized()
+}
+
+func (ba *dynamicArray) Freeze() error {
+	ba.lock()
+	defer ba.unlock()
+	return ba.impl.Freeze()
+}
+
+func (ba *dynamicArray) Flush() error {
+	ba.lock()
+	defer ba.unlock()
+	if err := ba.touch(); err != nil {
+		return err
+	}
+	return ba.impl.Flush()
+}
+
+func (ba *dynamicArray) Sync() error {
+	ba.lock()
+	defer ba.unlock()
+	if err := ba.touch(); err != nil {
+		return err
+	}
+	return ba.impl.Sync()
+}
+
+func (ba *dynamicArray) Close() error {
+	ba.lock()
+	defer ba.unlock()
+	if ba.o.budget != nil {
+		ba.o.budget.untrack(ba)
+	}
+	ba.closed = true
+	return ba.impl.Close()
+}
+
+func (ba *dynamicArray) Debug() string {
+	return debugImpl(ba)
+}
+
+func (ba *dynamicArray) Stats() Stats {
+	ba.lock()
+	defer ba.unlock()
+	return StatsOf(ba.impl)
+}
+
+func (ba *dynamicArray) WriteTo(w io.Writer) (int64, error) {
+	ba.lock()
+	defer ba.unlock()
+	wt, ok := ba.impl.(io.WriterTo)
+	if !ok {
+		return 0, &NotImplementedError{Op: "WriteTo"}
+	}
+	return wt.WriteTo(w)
+}
+
+func (ba *dynamicArray) MarshalBinary() ([]byte, error) {
+	return marshalImpl(ba)
+}
+
+// UnmarshalBinary replaces the contents of the array.  Arrays kept in a file
+// supplied through WithFile are not supported, as the file would be
+// abandoned.
+func (ba *dynamicArray) UnmarshalBinary(data []byte) error {
+	ba.lock()
+	defer ba.unlock()
+	if ba.impl.Frozen() {
+		panic("BigArray is read-only")
+	}
+	if x, ok := ba.impl.(*onDiskArray); ok && !x.doc {
+		return &NotImplementedError{Op: "UnmarshalBinary"}
+	}
+	err := ba.replace(func(old BigArray) (BigArray, error) {
+		impl, err := unmarshalImpl(data, ba.o, old, newImpl)
+		if err != nil {
+			return nil, err
+		}
+		if err := old.Close(); err != nil {
+			impl.Close()
+			return nil, err
+		}
+		return impl, nil
+	})
+	if err != nil {
+		return err
+	}
+	return ba.resized()
+}
+
+func (ba *dynamicArray) lock() {
+	ba.mu <- struct{}{}
+}
+
+func (ba *dynamicArray) unlock() {
+	<-ba.mu
+}
+
+// tryLock takes the lock if it is free, and returns false otherwise.
+func (ba *dynamicArray) tryLock() bool {
+	select {
+	case ba.mu <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// ensureFits widens the array, if necessary and permitted, so that it can
+// hold the given value.
+func (ba *dynamicArray) ensureFits(value uint64) error {
+	if value <= ba.impl.MaxValue() || !ba.o.autoWiden || ba.impl.Frozen() {
+		return nil
+	}
+	err := ba.replace(func(old BigArray) (BigArray, error) {
+		return widenImpl(old, ba.o, calcMaxToBPV(value))
+	})
+	if err == nil && ba.o.budget != nil {
+		ba.o.budget.adjust(ba, ba.heldBytes())
+	}
+	return err
+}
+
+// touch records a use of the array with its MemoryBudget, and moves the array
+// to disk if the budget has asked for that.  The lock must be held.
+func (ba *dynamicArray) touch() error {
+	mb := ba.o.budget
+	if mb == nil {
+		return nil
+	}
+	atomic.StoreUint64(&ba.lastUse, mb.tick())
+	if atomic.LoadInt32(&ba.spill) == 0 || ba.busy != 0 {
+		return nil
+	}
+	return ba.demote()
+}
+
+// heldBytes returns the number of bytes of memory held by the array, to be
+// charged to its MemoryBudget.
+func (ba *dynamicArray) heldBytes() uint64 {
+	return inMemoryBytes(ba.impl) + ba.dropped
+}
+
+// replace swaps in a new representation of the array.  Outstanding iterators
+// are suspended before the swap and resumed at the same position afterward.
+func (ba *dynamicArray) replace(fn func(BigArray) (BigArray, error)) error {
+	var finalError error
+	for iter := range ba.iters {
+		if err := iter.suspend(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	if finalError == nil {
+		impl, err := fn(ba.impl)
+		if err != nil {
+			finalError = err
+		} else {
+			ba.impl = impl
+			ba.dropped = 0
+		}
+	}
+	for iter := range ba.iters {
+		iter.resume(finalError)
+	}
+	return finalError
+}
+
+// lockImpl returns the current representation of (ba), with the lock held if
+// (ba) is a stable handle, along with the function which releases the lock.
+// Code which reaches past the handle holds the lock so that the MemoryBudget
+// cannot move the array to disk underneath it.  Methods of the handle must not
+// be called until the lock is released.
+func lockImpl(ba BigArray) (BigArray, func()) {
+	if x, ok := ba.(*dynamicArray); ok {
+		x.lock()
+		return x.impl, x.unlock
+	}
+	return unwrapArray(ba), func() {}
+}
+
+var _ BigArray = (*dynamicArray)(nil)
+
+// widenImpl re-encodes an array using the given number of bytes per value.
+//
+// In-memory arrays are copied into a freshly allocated array, which may land
+// on disk if the wider encoding crosses the OnDiskThreshold.  On-disk arrays
+// are rewritten in place within their existing backing file.
+func widenImpl(old BigArray, o options, bpv byte) (BigArray, error) {
+	o.bytesPerValue = bpv
+	o.maxValue = calcBPVToMax(bpv)
+	o.populatePaging(uint(bpv), "value")
+
+	if x, ok := old.(*onDiskArray); ok {
+		if err := x.Flush(); err != nil {
+			return nil, err
+		}
+		if len(x.cache) != 0 {
+			panic("BUG: widening an onDiskArray with live pages")
+		}
+		if err := widenFile(x.f, x.base, x.num, x.bpv, bpv, o.pageSize); err != nil {
+			return nil, err
+		}
+		ba := &onDiskArray{
+			f:     x.f,
+			p:     x.p,
+			cache: make(map[uint64]*cachePage),
+			st:    x.st,
+			mb:    x.mb,
+			dur:   x.dur,
+			base:  x.base,
+			num:   x.num,
+			max:   o.maxValue,
+			psz:   o.pageSize,
+			bpv:   bpv,
+			ro:    x.ro,
+			doc:   x.doc,
+		}
+		return ba, nil
+	}
+
+	o.numValues = old.Len()
+	ba, err := newImpl(o)
+	if err != nil {
+		return nil, err
+	}
+	if err := ba.CopyFrom(old); err != nil {
+		ba.Close()
+		return nil, err
+	}
+	if err := old.Close(); err != nil {
+		ba.Close()
+		return nil, err
+	}
+	return ba, nil
+}
+
+// relocateImpl copies an array into a new array with the same width and
+// contents, held in memory if (inMemory) is true or on disk otherwise.  The
+// old array is closed.
+func relocateImpl(old BigArray, o options, inMemory bool) (BigArray, error) {
+	o.numValues = old.Len()
+	o.maxValue = old.MaxValue()
+	o.bytesPerValue = bytesPerValueOf(old)
+	o.isReadOnly = false
+	o.backingFile = nil
+	o.diskThreshold = 0
+	if inMemory {
+		o.diskThreshold = ^uint64(0)
+	}
+
+	ba, err := newImpl(o)
+	if err != nil {
+		return nil, err
+	}
+	if err := ba.CopyFrom(old); err != nil {
+		ba.Close()
+		return nil, err
+	}
+	if old.Frozen() {
+		if err := ba.Freeze(); err != nil {
+			ba.Close()
+			return nil, err
+		}
+	}
+	if err := old.Close(); err != nil {
+		ba.Close()
+		return nil, err
+	}
+	return ba, nil
+}
+
+// bytesPerValueOf returns the width of the encoding used by an in-memory or
+// on-disk array.
+func bytesPerValueOf(ba BigArray) byte {
+	switch x := ba.(type) {
+	case *inMemoryArray8:
+		return 1
+	case *inMemoryArray16:
+		return 2
+	case *inMemoryArray32:
+		return 4
+	case *inMemoryArray64:
+		return 8
+	case *onDiskArray:
+		return x.bpv
+	default:
+		return calcMaxToBPV(ba.MaxValue())
+	}
+}
+
+// widenFile rewrites (num) values in place, starting at byte offset (base),
+// from an encoding of (oldBPV) bytes per value to an encoding of (newBPV)
+// bytes per value.
+//
+// The values are processed from the end of the file toward the beginning.
+// Because each value moves to an offset at least as large as its old one,
+// no value is overwritten before it has been read.
+func widenFile(f File, base, num uint64, oldBPV, newBPV byte, psz uint) error {
+	ob := uint64(oldBPV)
+	nb := uint64(newBPV)
+	chunk := uint64(psz) / nb
+	if chunk == 0 {
+		chunk = 1
+	}
+
+	if err := f.Truncate(int64(base + num*nb)); err != nil {
+		return err
+	}
+
+	oldBuf := make([]byte, chunk*ob)
+	newBuf := make([]byte, chunk*nb)
+	for end := num; end > 0; {
+		start := uint64(0)
+		if end > chunk {
+			start = end - chunk
+		}
+		n := end - start
+
+		_, err := f.ReadAt(oldBuf[0:n*ob], int64(base+start*ob))
+		if err != nil && err != io.EOF {
+			return err
+		}
+		for k := uint64(0); k < n; k++ {
+			value := bpvDecode(oldBPV, oldBuf[k*ob:(k+1)*ob])
+			bpvEncode(newBPV, newBuf[k*nb:(k+1)*nb], value)
+		}
+		_, err = f.WriteAt(newBuf[0:n*nb], int64(base+start*nb))
+		if err != nil {
+			return err
+		}
+		end = start
+	}
+	return nil
+}
+
+type dynamicIterator struct {
+	ba     *dynamicArray
+	iter   Iterator
+	err    error
+	i      uint64
+	j      uint64
+	steps  uint64
+	primed bool
+	done   bool
+	down   bool
+}
+
+func (iter *dynamicIterator) Next() bool { return iter.Skip(1) }
+
+func (iter *dynamicIterator) Err() error {
+	if iter.err != nil {
+		return iter.err
+	}
+	return iter.iter.Err()
+}
+
+func (iter *dynamicIterator) Index() uint64 { return iter.iter.Index() }
+func (iter *dynamicIterator) Value() uint64 { return iter.iter.Value() }
+func (iter *dynamicIterator) Valid() bool   { return iter.iter.Valid() }
+
+func (iter *dynamicIterator) SetValue(value uint64) {
+	if iter.err != nil {
+		return
+	}
+	if err := iter.ba.ensureFits(value); err != nil {
+		iter.err = err
+		return
+	}
+	iter.iter.SetValue(value)
+}
+
+func (iter *dynamicIterator) Skip(n uint64) bool {
+	if iter.err != nil || iter.done {
+		return false
+	}
+	if !iter.iter.Skip(n) {
+		iter.err = iter.iter.Err()
+		iter.done = true
+		return false
+	}
+	iter.primed = true
+	iter.steps += n
+	return true
+}
+
+func (iter *dynamicIterator) Flush() error {
+	if iter.err != nil {
+		return iter.err
+	}
+	return iter.iter.Flush()
+}
+
+func (iter *dynamicIterator) Close() error {
+	if iter.ba == nil {
+		return iter.err
+	}
+	iter.ba.lock()
+	delete(iter.ba.iters, iter)
+	err := iter.iter.Close()
+	iter.ba.unlock()
+	if iter.err != nil {
+		err = iter.err
+	}
+	*iter = dynamicIterator{err: ErrClosedIterator}
+	return err
+}
+
+// suspend flushes and releases the underlying iterator, remembering its
+// position so that resume can recreate it.
+func (iter *dynamicIterator) suspend() error {
+	err := iter.iter.Close()
+	if err != nil && iter.err == nil {
+		iter.err = err
+	}
+	return err
+}
+
+// resume recreates the underlying iterator against the array's current
+// representation, or poisons the iterator if (err) is non-nil.
+func (iter *dynamicIterator) resume(err error) {
+	if err != nil && iter.err == nil {
+		iter.err = err
+	}
+	if iter.down {
+		iter.iter = iter.ba.impl.ReverseIterate(iter.i, iter.j)
+	} else {
+		iter.iter = iter.ba.impl.Iterate(iter.i, iter.j)
+	}
+	if iter.err != nil || iter.done || !iter.primed {
+		return
+	}
+	if !iter.iter.Skip(iter.steps) {
+		iter.err = iter.iter.Err()
+		if iter.err == nil {
+			iter.err = io.ErrUnexpectedEOF
+		}
+	}
+}
+
+var _ Iterator = (*dynamicIterator)(nil)
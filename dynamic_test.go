@@ -0,0 +1,91 @@
+package bigarray
+
+import (
+	"testing"
+)
+
+func RunAutoWidenTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	opts = append(opts,
+		AutoWiden(),
+		MaxValue(100),
+		PageSize(16),
+		NumValues(40))
+
+	ba, err := New(opts...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+
+	if ba.MaxValue() != 0xff {
+		t.Errorf("BigArray.MaxValue: expected 255, got %d", ba.MaxValue())
+	}
+
+	other := ba.ReverseIterate(0, ba.Len())
+	if !other.Next() || !other.Next() {
+		t.Errorf("BigArray.ReverseIterate: error: %v", other.Err())
+	}
+
+	iter := ba.Iterate(0, ba.Len())
+	for iter.Next() {
+		value := iter.Index()
+		if value == 10 {
+			value = 1000
+		}
+		iter.SetValue(value)
+		if iter.Value() != value {
+			t.Errorf("Iterator.Value [%d]: expected %d, got %d", iter.Index(), value, iter.Value())
+		}
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("BigArray.Iterate: error: %v", err)
+	}
+	if ba.MaxValue() != 0xffff {
+		t.Errorf("BigArray.MaxValue: expected 65535, got %d", ba.MaxValue())
+	}
+
+	if other.Index() != 38 {
+		t.Errorf("migrated Iterator.Index: expected 38, got %d", other.Index())
+	}
+	if !other.Next() || other.Index() != 37 || other.Value() != 37 {
+		t.Errorf("migrated Iterator.Next: expected [37]=37, got err=%v", other.Err())
+	}
+	if err := other.Close(); err != nil {
+		t.Errorf("migrated Iterator.Close: error: %v", err)
+	}
+
+	if err := ba.SetValueAt(5, 1<<40); err != nil {
+		t.Errorf("BigArray.SetValueAt 5: error: %v", err)
+	}
+	if ba.MaxValue() != ^uint64(0) {
+		t.Errorf("BigArray.MaxValue: expected 2^64-1, got %d", ba.MaxValue())
+	}
+
+	for i := uint64(0); i < ba.Len(); i++ {
+		expect := i
+		switch i {
+		case 5:
+			expect = 1 << 40
+		case 10:
+			expect = 1000
+		}
+		value, err := ba.ValueAt(i)
+		if err != nil {
+			t.Errorf("BigArray.ValueAt %d: error: %v", i, err)
+		}
+		if value != expect {
+			t.Errorf("BigArray.ValueAt %d: expected %d, got %d", i, expect, value)
+		}
+	}
+}
+
+func TestAutoWiden_InMemory(t *testing.T) {
+	RunAutoWidenTests(t)
+}
+
+func TestAutoWiden_OnDisk(t *testing.T) {
+	RunAutoWidenTests(t, OnDiskThreshold(0))
+}
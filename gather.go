@@ -0,0 +1,210 @@
+package bigarray
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// gatherChunkSize is the number of indices which Gather and Scatter sort in
+// memory at once.
+const gatherChunkSize = 1 << 20
+
+type gatherEntry struct {
+	pos   uint64
+	index uint64
+	value uint64
+}
+
+// Gather sets dst[k] = src[idx[k]] for every k.
+//
+// (dst) and (idx) must have the same length.  Every element of (idx) must be
+// a valid index into (src), and no gathered value may exceed dst.MaxValue().
+//
+// Rather than reading (src) in the random order dictated by (idx), Gather
+// processes the indices in chunks, sorting each chunk by index so that (src)
+// is scanned in a single forward pass per chunk.  (idx) and (dst) are accessed
+// sequentially.  When (idx) fits in one chunk, each page of (src) is read at
+// most once.
+//
+func Gather(dst, src, idx BigArray) error {
+	if dst.Frozen() {
+		panic("BigArray is read-only")
+	}
+	if dst.Len() != idx.Len() {
+		panic("big arrays are not equal in size")
+	}
+
+	num := idx.Len()
+	entries := make([]gatherEntry, 0, chunkLen(num))
+	values := make([]uint64, chunkLen(num))
+	for k0 := uint64(0); k0 < num; k0 += gatherChunkSize {
+		k1 := k0 + chunkLen(num-k0)
+
+		entries = entries[:0]
+		err := readChunk(idx, k0, k1, func(pos, index uint64) {
+			entries = append(entries, gatherEntry{pos: pos, index: index})
+		})
+		if err != nil {
+			return err
+		}
+
+		sort.Slice(entries, func(a, b int) bool {
+			return entries[a].index < entries[b].index
+		})
+		if err := gatherChunk(src, entries, values, k0); err != nil {
+			return err
+		}
+
+		iter := dst.Iterate(k0, k1)
+		for n := 0; iter.Next(); n++ {
+			iter.SetValue(values[n])
+		}
+		if err := iter.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Scatter sets dst[idx[k]] = src[k] for every k.
+//
+// (src) and (idx) must have the same length.  Every element of (idx) must be
+// a valid index into (dst), and no element of (src) may exceed
+// dst.MaxValue().  If (idx) contains duplicates, the element with the largest
+// k wins.
+//
+// Like Gather, Scatter sorts each chunk of indices so that (dst) is written in
+// a single forward pass per chunk, while (idx) and (src) are read
+// sequentially.
+//
+func Scatter(dst, src, idx BigArray) error {
+	if dst.Frozen() {
+		panic("BigArray is read-only")
+	}
+	if src.Len() != idx.Len() {
+		panic("big arrays are not equal in size")
+	}
+
+	num := idx.Len()
+	entries := make([]gatherEntry, 0, chunkLen(num))
+	for k0 := uint64(0); k0 < num; k0 += gatherChunkSize {
+		k1 := k0 + chunkLen(num-k0)
+
+		entries = entries[:0]
+		err := readChunk(idx, k0, k1, func(pos, index uint64) {
+			entries = append(entries, gatherEntry{pos: pos, index: index})
+		})
+		if err != nil {
+			return err
+		}
+		err = readChunk(src, k0, k1, func(pos, value uint64) {
+			entries[pos-k0].value = value
+		})
+		if err != nil {
+			return err
+		}
+
+		sort.SliceStable(entries, func(a, b int) bool {
+			return entries[a].index < entries[b].index
+		})
+		if err := scatterChunk(dst, entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func chunkLen(n uint64) uint64 {
+	if n > gatherChunkSize {
+		return gatherChunkSize
+	}
+	return n
+}
+
+func readChunk(ba BigArray, i, j uint64, fn func(uint64, uint64)) error {
+	iter := ba.Iterate(i, j)
+	for iter.Next() {
+		fn(iter.Index(), iter.Value())
+	}
+	return iter.Close()
+}
+
+// gatherChunk reads the value for each entry from (src) into values[pos-base].
+// The entries must be sorted by index.
+func gatherChunk(src BigArray, entries []gatherEntry, values []uint64, base uint64) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	lo := entries[0].index
+	hi := entries[len(entries)-1].index
+	if hi >= src.Len() {
+		return fmt.Errorf("index %d out of range for array of length %d", hi, src.Len())
+	}
+
+	iter := src.Iterate(lo, hi+1)
+	cur := lo
+	ok := iter.Next()
+	for n := range entries {
+		if !ok {
+			break
+		}
+		if delta := entries[n].index - cur; delta > 0 {
+			ok = iter.Skip(delta)
+			cur = entries[n].index
+			if !ok {
+				break
+			}
+		}
+		values[entries[n].pos-base] = iter.Value()
+	}
+	return closeChunk(iter, ok)
+}
+
+// scatterChunk writes the value of each entry into (dst).  The entries must be
+// sorted by index.
+func scatterChunk(dst BigArray, entries []gatherEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	lo := entries[0].index
+	hi := entries[len(entries)-1].index
+	if hi >= dst.Len() {
+		return fmt.Errorf("index %d out of range for array of length %d", hi, dst.Len())
+	}
+
+	iter := dst.Iterate(lo, hi+1)
+	cur := lo
+	ok := iter.Next()
+	for n := range entries {
+		if !ok {
+			break
+		}
+		if delta := entries[n].index - cur; delta > 0 {
+			ok = iter.Skip(delta)
+			cur = entries[n].index
+			if !ok {
+				break
+			}
+		}
+		iter.SetValue(entries[n].value)
+	}
+	return closeChunk(iter, ok)
+}
+
+// closeChunk closes an Iterator used by gatherChunk or scatterChunk.  If the
+// Iterator ended before every entry was visited, it reports an error rather
+// than leaving the remaining entries silently untouched.
+func closeChunk(iter Iterator, ok bool) error {
+	if ok {
+		return iter.Close()
+	}
+	err := iter.Err()
+	if err2 := iter.Close(); err == nil {
+		err = err2
+	}
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package bigarray
+
+import (
+	"io"
+	"testing"
+)
+
+func RunGatherScatterTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	newArray := func(num uint64) BigArray {
+		ba, err := New(append(opts, MaxValue(1000), PageSize(8), NumValues(num))...)
+		if err != nil {
+			t.Fatalf("New: error: %v", err)
+		}
+		return ba
+	}
+
+	src := newArray(50)
+	defer src.Close()
+	for i := uint64(0); i < src.Len(); i++ {
+		src.SetValueAt(i, i*10)
+	}
+
+	idx := newArray(30)
+	defer idx.Close()
+	for k := uint64(0); k < idx.Len(); k++ {
+		idx.SetValueAt(k, (k*17)%50)
+	}
+	idx.SetValueAt(29, 0)
+
+	dst := newArray(30)
+	defer dst.Close()
+	if err := Gather(dst, src, idx); err != nil {
+		t.Errorf("Gather: error: %v", err)
+	}
+	for k := uint64(0); k < dst.Len(); k++ {
+		index, _ := idx.ValueAt(k)
+		value, _ := dst.ValueAt(k)
+		if value != index*10 {
+			t.Errorf("Gather: dst[%d]: expected %d, got %d", k, index*10, value)
+		}
+	}
+
+	out := newArray(50)
+	defer out.Close()
+	if err := Scatter(out, dst, idx); err != nil {
+		t.Errorf("Scatter: error: %v", err)
+	}
+	for k := uint64(0); k < idx.Len(); k++ {
+		index, _ := idx.ValueAt(k)
+		value, _ := out.ValueAt(index)
+		if value != index*10 {
+			t.Errorf("Scatter: out[%d]: expected %d, got %d", index, index*10, value)
+		}
+	}
+
+	idx.SetValueAt(3, 999)
+	if err := Gather(dst, src, idx); err == nil {
+		t.Error("Gather: expected error for out-of-range index, got nil")
+	}
+}
+
+func TestGatherScatter_InMemory(t *testing.T) {
+	RunGatherScatterTests(t)
+}
+
+func TestGatherScatter_OnDisk(t *testing.T) {
+	RunGatherScatterTests(t, OnDiskThreshold(0))
+}
+
+// shortArray is a BigArray whose Iterators stop early, at index (stop).
+type shortArray struct {
+	BigArray
+	stop uint64
+}
+
+func (ba shortArray) Iterate(i, j uint64) Iterator {
+	if j > ba.stop {
+		j = ba.stop
+	}
+	return ba.BigArray.Iterate(i, j)
+}
+
+func TestGatherScatter_ShortIterator(t *testing.T) {
+	ba, err := New(MaxValue(255), NumValues(10))
+	if err != nil {
+		t.Fatalf("New: error: %v", err)
+	}
+	defer ba.Close()
+	idx, err := New(MaxValue(255), NumValues(2))
+	if err != nil {
+		t.Fatalf("New: error: %v", err)
+	}
+	defer idx.Close()
+	idx.SetValueAt(0, 2)
+	idx.SetValueAt(1, 8)
+
+	dst, err := New(MaxValue(255), NumValues(2))
+	if err != nil {
+		t.Fatalf("New: error: %v", err)
+	}
+	defer dst.Close()
+	if err := Gather(dst, shortArray{ba, 5}, idx); err != io.ErrUnexpectedEOF {
+		t.Errorf("Gather: expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	if err := Scatter(shortArray{ba, 5}, dst, idx); err != io.ErrUnexpectedEOF {
+		t.Errorf("Scatter: expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
@@ -80,6 +80,9 @@ func (ba *inMemoryArray16) CopyFrom(src BigArray) error {
 		copy(ba.data, x.data)
 		return nil
 	}
+	if ok, err := copyFromFast(ba, src); ok {
+		return err
+	}
 	return copyFromImpl(ba, src)
 }
 
@@ -103,6 +106,10 @@ func (ba *inMemoryArray16) Flush() error {
 	return nil
 }
 
+func (ba *inMemoryArray16) Sync() error {
+	return nil
+}
+
 func (ba *inMemoryArray16) Close() error {
 	return nil
 }
@@ -111,4 +118,21 @@ func (ba *inMemoryArray16) Debug() string {
 	return debugImpl(ba)
 }
 
+func (ba *inMemoryArray16) WriteTo(w io.Writer) (int64, error) {
+	return writeToMem(w, ba, 2)
+}
+
+func (ba *inMemoryArray16) MarshalBinary() ([]byte, error) {
+	return marshalImpl(ba)
+}
+
+func (ba *inMemoryArray16) UnmarshalBinary(data []byte) error {
+	if ba.ro {
+		panic("BigArray is read-only")
+	}
+	return unmarshalMem(ba, data, func(n uint64) {
+		ba.data = make([]uint16, n)
+	})
+}
+
 var _ BigArray = (*inMemoryArray16)(nil)
@@ -80,6 +80,9 @@ func (ba *inMemoryArray64) CopyFrom(src BigArray) error {
 		copy(ba.data, x.data)
 		return nil
 	}
+	if ok, err := copyFromFast(ba, src); ok {
+		return err
+	}
 	return copyFromImpl(ba, src)
 }
 
@@ -103,6 +106,10 @@ func (ba *inMemoryArray64) Flush() error {
 	return nil
 }
 
+func (ba *inMemoryArray64) Sync() error {
+	return nil
+}
+
 func (ba *inMemoryArray64) Close() error {
 	return nil
 }
@@ -111,4 +118,21 @@ func (ba *inMemoryArray64) Debug() string {
 	return debugImpl(ba)
 }
 
+func (ba *inMemoryArray64) WriteTo(w io.Writer) (int64, error) {
+	return writeToMem(w, ba, 8)
+}
+
+func (ba *inMemoryArray64) MarshalBinary() ([]byte, error) {
+	return marshalImpl(ba)
+}
+
+func (ba *inMemoryArray64) UnmarshalBinary(data []byte) error {
+	if ba.ro {
+		panic("BigArray is read-only")
+	}
+	return unmarshalMem(ba, data, func(n uint64) {
+		ba.data = make([]uint64, n)
+	})
+}
+
 var _ BigArray = (*inMemoryArray64)(nil)
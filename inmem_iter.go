@@ -41,6 +41,16 @@ func (iter *inMemoryIterator) Value() uint64 {
 	return iter.val
 }
 
+func (iter *inMemoryIterator) Valid() bool {
+	if !iter.primed {
+		panic(fmt.Errorf("must call Next() before Valid()"))
+	}
+	if iter.pos >= iter.num {
+		panic(fmt.Errorf("must not call Valid() after Next() returns false"))
+	}
+	return true
+}
+
 func (iter *inMemoryIterator) SetValue(value uint64) {
 	if !iter.primed {
 		panic(fmt.Errorf("must call Next() before SetValue()"))
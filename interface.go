@@ -2,7 +2,6 @@ package bigarray
 
 import (
 	"errors"
-	"io/ioutil"
 )
 
 // ErrClosedIterator is returned when Iterator.Close() is called multiple times
@@ -57,6 +56,12 @@ type BigArray interface {
 	// Flush ensures that all pending writes have reached the OS.
 	Flush() error
 
+	// Sync flushes pending writes and then asks the OS to commit them to
+	// stable storage, so that they survive a crash.  Sync is a no-op for
+	// in-memory arrays and for files from WithReadOnlyFile.  See also the
+	// Durability option.
+	Sync() error
+
 	// Close flushes any writes and frees the resources used by the array.
 	Close() error
 
@@ -96,6 +101,10 @@ type Iterator interface {
 	// Value returns the value of the current element.
 	Value() uint64
 
+	// Valid returns false if the current element is null.  Elements of
+	// arrays created without the Nullable option are always valid.
+	Valid() bool
+
 	// SetValue replaces the value of the current element.
 	SetValue(uint64)
 
@@ -115,6 +124,20 @@ func New(opts ...Option) (BigArray, error) {
 	o.apply(opts...)
 	o.populate()
 
+	return build(o)
+}
+
+func build(o options) (BigArray, error) {
+	if o.isNullable {
+		return newNullableArray(o)
+	}
+	if o.autoWiden || o.migratable || o.budget != nil {
+		return newDynamicArray(o)
+	}
+	return newImpl(o)
+}
+
+func newImpl(o options) (BigArray, error) {
 	numBytes := o.numValues * uint64(o.bytesPerValue)
 	if o.backingFile == nil && numBytes < o.diskThreshold {
 		var ba BigArray
@@ -156,13 +179,8 @@ func New(opts ...Option) (BigArray, error) {
 	doc := false
 	if o.backingFile == nil {
 		var err error
-		o.backingFile, err = ioutil.TempFile("", "tmp")
-		if err != nil {
-			return nil, err
-		}
-		err = o.backingFile.Truncate(int64(numBytes))
+		o.backingFile, err = createTempFile(o, numBytes)
 		if err != nil {
-			removeFile(o.backingFile)
 			return nil, err
 		}
 		doc = true
@@ -172,6 +190,9 @@ func New(opts ...Option) (BigArray, error) {
 		f:     o.backingFile,
 		p:     o.bufferPool,
 		cache: make(map[uint64]*cachePage),
+		st:    newIOStats(o.observer),
+		mb:    o.budget,
+		dur:   newDurability(o, doc),
 		num:   o.numValues,
 		max:   o.maxValue,
 		psz:   o.pageSize,
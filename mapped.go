@@ -0,0 +1,137 @@
+package bigarray
+
+import (
+	"fmt"
+)
+
+// Map returns a read-only view whose elements are the elements of (ba)
+// transformed by (fn).  The transformation is applied on the fly by ValueAt
+// and by Iterators, so no intermediate array is materialized.
+//
+// The view's MaxValue() is (max).  The caller promises that (fn) never
+// returns a value larger than (max).  Null elements are passed through
+// without calling (fn).
+//
+// Close affects only the view, as for Slice.
+//
+func Map(ba BigArray, fn func(uint64) uint64, max uint64) BigArray {
+	return &mappedArray{ba: ba, fn: fn, max: max}
+}
+
+// Materialize writes the elements of (view) into a new array.
+//
+// The options are interpreted as for Convert: NumValues is taken from the
+// view, MaxValue defaults to the view's MaxValue(), and the usual
+// OnDiskThreshold rules decide whether the new array lives in memory or on
+// disk.
+//
+func Materialize(view BigArray, opts ...Option) (BigArray, error) {
+	return Convert(view, opts...)
+}
+
+type mappedArray struct {
+	ba  BigArray
+	fn  func(uint64) uint64
+	max uint64
+}
+
+func (view *mappedArray) Frozen() bool {
+	return true
+}
+
+func (view *mappedArray) MaxValue() uint64 {
+	return view.max
+}
+
+func (view *mappedArray) Len() uint64 {
+	return view.ba.Len()
+}
+
+func (view *mappedArray) ValueAt(index uint64) (uint64, error) {
+	value, err := view.ba.ValueAt(index)
+	if err != nil {
+		return value, err
+	}
+	if nba, ok := view.ba.(NullableArray); ok {
+		if null, err := nba.IsNull(index); err != nil || null {
+			return value, err
+		}
+	}
+	return view.fn(value), nil
+}
+
+func (view *mappedArray) SetValueAt(index uint64, value uint64) error {
+	panic("BigArray is read-only")
+}
+
+func (view *mappedArray) Iterate(i, j uint64) Iterator {
+	if i > j {
+		panic(fmt.Errorf("mappedArray.Iterate: i > j: i=%d j=%d", i, j))
+	}
+	return &mappedIterator{iter: view.ba.Iterate(i, j), fn: view.fn}
+}
+
+func (view *mappedArray) ReverseIterate(i, j uint64) Iterator {
+	if i > j {
+		panic(fmt.Errorf("mappedArray.ReverseIterate: i > j: i=%d j=%d", i, j))
+	}
+	return &mappedIterator{iter: view.ba.ReverseIterate(i, j), fn: view.fn}
+}
+
+func (view *mappedArray) CopyFrom(src BigArray) error {
+	panic("BigArray is read-only")
+}
+
+func (view *mappedArray) Truncate(n uint64) error {
+	panic("BigArray is read-only")
+}
+
+func (view *mappedArray) Freeze() error {
+	return nil
+}
+
+func (view *mappedArray) Flush() error {
+	return nil
+}
+
+func (view *mappedArray) Sync() error {
+	return nil
+}
+
+// Close does nothing.  The parent array remains open.
+func (view *mappedArray) Close() error {
+	return nil
+}
+
+func (view *mappedArray) Debug() string {
+	return debugImpl(view)
+}
+
+var _ BigArray = (*mappedArray)(nil)
+
+type mappedIterator struct {
+	iter Iterator
+	fn   func(uint64) uint64
+}
+
+func (iter *mappedIterator) Next() bool         { return iter.iter.Next() }
+func (iter *mappedIterator) Skip(n uint64) bool { return iter.iter.Skip(n) }
+func (iter *mappedIterator) Index() uint64      { return iter.iter.Index() }
+func (iter *mappedIterator) Valid() bool        { return iter.iter.Valid() }
+func (iter *mappedIterator) Err() error         { return iter.iter.Err() }
+func (iter *mappedIterator) Flush() error       { return nil }
+func (iter *mappedIterator) Close() error       { return iter.iter.Close() }
+
+func (iter *mappedIterator) Value() uint64 {
+	value := iter.iter.Value()
+	if !iter.iter.Valid() {
+		return value
+	}
+	return iter.fn(value)
+}
+
+func (iter *mappedIterator) SetValue(value uint64) {
+	panic("BigArray is read-only")
+}
+
+var _ Iterator = (*mappedIterator)(nil)
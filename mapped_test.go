@@ -0,0 +1,61 @@
+package bigarray
+
+import (
+	"testing"
+)
+
+func RunMapTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	ba, err := New(append(opts, MaxValue(100), PageSize(8), NumValues(20))...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+
+	for i := uint64(0); i < ba.Len(); i++ {
+		ba.SetValueAt(i, i)
+	}
+
+	view := Map(ba, func(v uint64) uint64 { return v * 1000 }, 100000)
+	if !view.Frozen() {
+		t.Error("Map.Frozen: expected true, got false")
+	}
+	if value, err := view.ValueAt(7); err != nil || value != 7000 {
+		t.Errorf("Map.ValueAt 7: expected 7000, got %d, %v", value, err)
+	}
+
+	m, err := Materialize(view, opts...)
+	if err != nil {
+		t.Errorf("Materialize: error: %v", err)
+		return
+	}
+	defer m.Close()
+
+	if m.MaxValue() != 100000 || m.Frozen() {
+		t.Errorf("Materialize: expected writable array with MaxValue 100000, got %d", m.MaxValue())
+	}
+	n := uint64(0)
+	err = ReverseForEach(m, func(index, value uint64) error {
+		if value != index*1000 {
+			t.Errorf("Materialize: [%d]: expected %d, got %d", index, index*1000, value)
+		}
+		n++
+		return nil
+	})
+	if err != nil {
+		t.Errorf("ReverseForEach: error: %v", err)
+	}
+	if n != ba.Len() {
+		t.Errorf("ReverseForEach only produced %d values", n)
+	}
+}
+
+func TestMap_InMemory(t *testing.T) {
+	RunMapTests(t)
+}
+
+func TestMap_OnDisk(t *testing.T) {
+	RunMapTests(t, OnDiskThreshold(0))
+}
@@ -0,0 +1,461 @@
+package bigarray
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strconv"
+)
+
+// The serialized form of an array, as written by WriteTo and read by
+// ReadFrom, is:
+//
+//   - a 24-byte header: the magic "BGAR", a version byte, the BytesPerValue,
+//     a flags byte, a reserved byte, then MaxValue and Len as little-endian
+//     uint64s
+//   - the elements, encoded as for an on-disk array
+//   - a little-endian CRC-32 (IEEE) of the encoded elements
+//
+// If the nullable flag is set, the elements are followed by the validity
+// bitset of a NullableArray, one bit per element as described for
+// nullableArray, and then by a CRC-32 of the bitset.
+//
+const (
+	marshalMagic      = "BGAR"
+	marshalVersion    = 1
+	marshalHeaderSize = 24
+
+	marshalFlagNullable = 0x01
+)
+
+// ErrBadFormat is returned by ReadFrom and UnmarshalBinary when the input is
+// not a serialized array.
+var ErrBadFormat = errors.New("not a serialized big array")
+
+// ErrChecksumMismatch is returned by ReadFrom and UnmarshalBinary when the
+// checksum of the elements does not match the trailer.
+var ErrChecksumMismatch = errors.New("big array checksum mismatch")
+
+type marshalHeader struct {
+	bpv      byte
+	max      uint64
+	num      uint64
+	nullable bool
+}
+
+func (h marshalHeader) encode() []byte {
+	buf := make([]byte, marshalHeaderSize)
+	copy(buf[0:4], marshalMagic)
+	buf[4] = marshalVersion
+	buf[5] = h.bpv
+	if h.nullable {
+		buf[6] |= marshalFlagNullable
+	}
+	binary.LittleEndian.PutUint64(buf[8:16], h.max)
+	binary.LittleEndian.PutUint64(buf[16:24], h.num)
+	return buf
+}
+
+func decodeMarshalHeader(buf []byte) (marshalHeader, error) {
+	var h marshalHeader
+	if len(buf) < marshalHeaderSize || string(buf[0:4]) != marshalMagic {
+		return h, ErrBadFormat
+	}
+	if buf[4] != marshalVersion {
+		return h, fmt.Errorf("unsupported big array version %d", buf[4])
+	}
+	h.bpv = buf[5]
+	h.nullable = (buf[6] & marshalFlagNullable) != 0
+	h.max = binary.LittleEndian.Uint64(buf[8:16])
+	h.num = binary.LittleEndian.Uint64(buf[16:24])
+	switch h.bpv {
+	case 1, 2, 4, 8:
+	default:
+		return h, ErrBadFormat
+	}
+	if h.max == 0 || h.max > calcBPVToMax(h.bpv) {
+		return h, ErrBadFormat
+	}
+	return h, nil
+}
+
+// marshalWriter counts the bytes written and checksums the elements.
+type marshalWriter struct {
+	w   io.Writer
+	n   int64
+	crc uint32
+}
+
+func (mw *marshalWriter) write(p []byte, isData bool) error {
+	n, err := mw.w.Write(p)
+	mw.n += int64(n)
+	if isData {
+		mw.crc = crc32.Update(mw.crc, crc32.IEEETable, p)
+	}
+	return err
+}
+
+// writeToImpl writes the header, then calls each of (fns) in turn to write a
+// section, such as the elements, followed by the section's checksum.
+func writeToImpl(w io.Writer, h marshalHeader, fns ...func(emit func([]byte) error) error) (int64, error) {
+	mw := &marshalWriter{w: w}
+	if err := mw.write(h.encode(), false); err != nil {
+		return mw.n, err
+	}
+	for _, fn := range fns {
+		mw.crc = 0
+		err := fn(func(p []byte) error {
+			return mw.write(p, true)
+		})
+		if err != nil {
+			return mw.n, err
+		}
+		var tmp [4]byte
+		binary.LittleEndian.PutUint32(tmp[:], mw.crc)
+		if err := mw.write(tmp[:], false); err != nil {
+			return mw.n, err
+		}
+	}
+	return mw.n, nil
+}
+
+// writeToMem implements WriteTo for the in-memory arrays, encoding the
+// elements in chunks.
+func writeToMem(w io.Writer, ba BigArray, bpv byte) (int64, error) {
+	num := ba.Len()
+	h := marshalHeader{bpv: bpv, max: ba.MaxValue(), num: num}
+	return writeToImpl(w, h, func(emit func([]byte) error) error {
+		buf := make([]uint64, chunkSize(num))
+		raw := make([]byte, len(buf)*int(bpv))
+		for off := uint64(0); off < num; off += uint64(len(buf)) {
+			chunk := buf[0:chunkSize(num-off)]
+			memDecode(ba, off, chunk)
+			rawEncode(bpv, raw, chunk)
+			if err := emit(raw[0 : len(chunk)*int(bpv)]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeToDisk implements WriteTo for on-disk arrays, streaming whole pages
+// through the page cache.  The array is flushed first, so that pages made
+// dirty by live Iterators can be shared.
+func writeToDisk(w io.Writer, ba *onDiskArray) (int64, error) {
+	if err := ba.Flush(); err != nil {
+		return 0, err
+	}
+	h := marshalHeader{bpv: ba.bpv, max: ba.max, num: ba.num}
+	return writeToImpl(w, h, func(emit func([]byte) error) error {
+		psz := uint64(ba.psz)
+		start := ba.base
+		end := ba.base + ba.num*uint64(ba.bpv)
+		for off := (start / psz) * psz; off < end; off += psz {
+			page, err := ba.acquirePage(off)
+			if err != nil {
+				return err
+			}
+			stop := off + psz
+			if stop > end {
+				stop = end
+			}
+			if off+uint64(len(page.data)) < stop {
+				ba.disposePage(page)
+				return io.ErrUnexpectedEOF
+			}
+			i := uint64(0)
+			if start > off {
+				i = start - off
+			}
+			err = emit(page.data[i : stop-off])
+			ba.disposePage(page)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// emitValues returns a section writer for writeToImpl which encodes the
+// elements of any BigArray, read through an Iterator.
+func emitValues(ba BigArray, bpv byte) func(emit func([]byte) error) error {
+	return func(emit func([]byte) error) error {
+		num := ba.Len()
+		raw := make([]byte, chunkSize(num)*uint64(bpv))
+		n := 0
+		iter := ba.Iterate(0, num)
+		for iter.Next() {
+			bpvEncode(bpv, raw[n:], iter.Value())
+			n += int(bpv)
+			if n == len(raw) {
+				if err := emit(raw); err != nil {
+					iter.Close()
+					return err
+				}
+				n = 0
+			}
+		}
+		if err := iter.Close(); err != nil {
+			return err
+		}
+		return emit(raw[0:n])
+	}
+}
+
+func marshalImpl(w io.WriterTo) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalMem implements UnmarshalBinary for the in-memory arrays.  Every
+// element is checked against MaxValue() before (resize) is called to replace
+// the array's storage with (n) zero elements.
+func unmarshalMem(ba BigArray, data []byte, resize func(n uint64)) error {
+	h, err := decodeMarshalHeader(data)
+	if err != nil {
+		return err
+	}
+	size := h.num * uint64(h.bpv)
+	if h.num > uint64(len(data)) || uint64(len(data)) != marshalHeaderSize+size+4 {
+		return ErrBadFormat
+	}
+	raw := data[marshalHeaderSize : marshalHeaderSize+size]
+	if crc32.ChecksumIEEE(raw) != binary.LittleEndian.Uint32(data[marshalHeaderSize+size:]) {
+		return ErrChecksumMismatch
+	}
+
+	max := ba.MaxValue()
+	buf := make([]uint64, chunkSize(h.num))
+	for off := uint64(0); off < h.num; off += uint64(len(buf)) {
+		chunk := buf[0:chunkSize(h.num-off)]
+		rawDecode(h.bpv, raw[off*uint64(h.bpv):], chunk)
+		for k, value := range chunk {
+			if value > max {
+				return &OutOfRangeError{Index: off + uint64(k), Value: value, Max: max}
+			}
+		}
+	}
+
+	resize(h.num)
+	for off := uint64(0); off < h.num; off += uint64(len(buf)) {
+		chunk := buf[0:chunkSize(h.num-off)]
+		rawDecode(h.bpv, raw[off*uint64(h.bpv):], chunk)
+		memEncode(ba, off, chunk)
+	}
+	return nil
+}
+
+// Info describes a serialized array, as recorded in its header.
+type Info struct {
+	Version       string
+	BytesPerValue uint8
+	MaxValue      uint64
+	Len           uint64
+	Nullable      bool
+}
+
+// ReadInfo reads the header of the serialized form written by WriteTo,
+// leaving (r) positioned at the first element.  The elements are not read or
+// checked.
+func ReadInfo(r io.Reader) (Info, error) {
+	h, err := readMarshalHeader(r)
+	if err != nil {
+		return Info{}, err
+	}
+	info := Info{
+		Version:       strconv.Itoa(marshalVersion),
+		BytesPerValue: h.bpv,
+		MaxValue:      h.max,
+		Len:           h.num,
+		Nullable:      h.nullable,
+	}
+	return info, nil
+}
+
+func readMarshalHeader(r io.Reader) (marshalHeader, error) {
+	head := make([]byte, marshalHeaderSize)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return marshalHeader{}, unexpectedEOF(err)
+	}
+	return decodeMarshalHeader(head)
+}
+
+// unmarshalImpl implements UnmarshalBinary for arrays which wrap another
+// representation.  It decodes (data) into a new array made by (newArray),
+// keeping the width and MaxValue() of (old) unless AutoWiden permits a wider
+// encoding.
+func unmarshalImpl(data []byte, o options, old BigArray, newArray func(options) (BigArray, error)) (BigArray, error) {
+	h, err := decodeMarshalHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	o.bytesPerValue = bytesPerValueOf(old)
+	o.maxValue = old.MaxValue()
+	if o.autoWiden && h.bpv > o.bytesPerValue {
+		o.bytesPerValue = h.bpv
+		o.maxValue = 0
+	}
+	o.isReadOnly = false
+	o.backingFile = nil
+
+	r := bytes.NewReader(data)
+	ba, err := readFrom(r, o, newArray)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		ba.Close()
+		return nil, ErrBadFormat
+	}
+	return ba, nil
+}
+
+// ReadFrom constructs a new array from the serialized form written by
+// WriteTo.
+//
+// NumValues and BytesPerValue are taken from the header.  If MaxValue is not
+// specified, it is also taken from the header; if it is specified and an
+// element exceeds it, an *OutOfRangeError is returned.  Whether the new array
+// is in-memory or on-disk is decided by OnDiskThreshold, as for New.  If the
+// array was a NullableArray, so is the new array.
+//
+func ReadFrom(r io.Reader, opts ...Option) (BigArray, error) {
+	var o options
+	o.apply(opts...)
+	o.bytesPerValue = 0
+	return readFrom(r, o, build)
+}
+
+// readFrom reads the serialized form of an array into a new array made by
+// (newArray).  NumValues, and BytesPerValue and MaxValue if they are not set
+// in (o), are taken from the header.
+func readFrom(r io.Reader, o options, newArray func(options) (BigArray, error)) (BigArray, error) {
+	h, err := readMarshalHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	o.numValues = h.num
+	if o.bytesPerValue == 0 {
+		o.bytesPerValue = h.bpv
+	}
+	if o.maxValue == 0 {
+		o.maxValue = h.max
+	}
+	if h.nullable {
+		o.isNullable = true
+	}
+	o.populate()
+
+	ba, err := newArray(o)
+	if err != nil {
+		return nil, err
+	}
+	if err := readFromImpl(r, ba, h); err != nil {
+		ba.Close()
+		return nil, err
+	}
+	return ba, nil
+}
+
+func readFromImpl(r io.Reader, ba BigArray, h marshalHeader) error {
+	x, ok := asNullable(ba)
+	if !ok {
+		return readSection(r, ba, h.num, h.bpv)
+	}
+	if err := readSection(r, x.data, h.num, h.bpv); err != nil {
+		return err
+	}
+	if h.nullable {
+		return readSection(r, x.bits, x.bits.Len(), 1)
+	}
+	return Fill(x.bits, 0, x.bits.Len(), 0xff)
+}
+
+// readSection reads (num) elements of (bpv) bytes each into (ba), followed by
+// their checksum.
+func readSection(r io.Reader, ba BigArray, num uint64, width byte) error {
+	max := ba.MaxValue()
+	bpv := uint64(width)
+
+	var crc uint32
+	buf := make([]uint64, chunkSize(num))
+	raw := make([]byte, uint64(len(buf))*bpv)
+	iter := ba.Iterate(0, num)
+	needClose := true
+	defer func() {
+		if needClose {
+			iter.Close()
+		}
+	}()
+	// The outstanding iterator keeps the MemoryBudget from moving (ba).
+	mem := isInMemory(unwrapArray(ba))
+
+	for off := uint64(0); off < num; off += uint64(len(buf)) {
+		chunk := buf[0:chunkSize(num-off)]
+		p := raw[0 : uint64(len(chunk))*bpv]
+		if _, err := io.ReadFull(r, p); err != nil {
+			return unexpectedEOF(err)
+		}
+		crc = crc32.Update(crc, crc32.IEEETable, p)
+		rawDecode(width, p, chunk)
+		for k, value := range chunk {
+			if value > max {
+				return &OutOfRangeError{Index: off + uint64(k), Value: value, Max: max}
+			}
+		}
+		if mem {
+			memEncode(unwrapArray(ba), off, chunk)
+			continue
+		}
+		for _, value := range chunk {
+			iter.Next()
+			iter.SetValue(value)
+		}
+	}
+	needClose = false
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	var tmp [4]byte
+	if _, err := io.ReadFull(r, tmp[:]); err != nil {
+		return unexpectedEOF(err)
+	}
+	if crc != binary.LittleEndian.Uint32(tmp[:]) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+var (
+	_ io.WriterTo                = (*inMemoryArray8)(nil)
+	_ io.WriterTo                = (*inMemoryArray16)(nil)
+	_ io.WriterTo                = (*inMemoryArray32)(nil)
+	_ io.WriterTo                = (*inMemoryArray64)(nil)
+	_ io.WriterTo                = (*onDiskArray)(nil)
+	_ io.WriterTo                = (*dynamicArray)(nil)
+	_ io.WriterTo                = (*nullableArray)(nil)
+	_ encoding.BinaryMarshaler   = (*inMemoryArray8)(nil)
+	_ encoding.BinaryMarshaler   = (*inMemoryArray16)(nil)
+	_ encoding.BinaryMarshaler   = (*inMemoryArray32)(nil)
+	_ encoding.BinaryMarshaler   = (*inMemoryArray64)(nil)
+	_ encoding.BinaryMarshaler   = (*onDiskArray)(nil)
+	_ encoding.BinaryMarshaler   = (*dynamicArray)(nil)
+	_ encoding.BinaryMarshaler   = (*nullableArray)(nil)
+	_ encoding.BinaryUnmarshaler = (*inMemoryArray8)(nil)
+	_ encoding.BinaryUnmarshaler = (*inMemoryArray16)(nil)
+	_ encoding.BinaryUnmarshaler = (*inMemoryArray32)(nil)
+	_ encoding.BinaryUnmarshaler = (*inMemoryArray64)(nil)
+	_ encoding.BinaryUnmarshaler = (*dynamicArray)(nil)
+	_ encoding.BinaryUnmarshaler = (*nullableArray)(nil)
+)
@@ -0,0 +1,221 @@
+package bigarray
+
+import (
+	"bytes"
+	"encoding"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func RunMarshalTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	for _, max := range []uint64{200, 60000, 1 << 30, 1 << 40} {
+		ba, err := New(append(opts, MaxValue(max), PageSize(16), NumValues(37))...)
+		if err != nil {
+			t.Errorf("New: error: %v", err)
+			return
+		}
+		for i := uint64(0); i < ba.Len(); i++ {
+			ba.SetValueAt(i, (i*7919)%max)
+		}
+		expect := ba.Debug()
+
+		wt, ok := ba.(io.WriterTo)
+		if !ok {
+			t.Errorf("max %d: %T does not implement io.WriterTo", max, ba)
+			ba.Close()
+			continue
+		}
+		var buf bytes.Buffer
+		n, err := wt.WriteTo(&buf)
+		if err != nil {
+			t.Errorf("max %d: WriteTo: error: %v", max, err)
+		}
+		bpv := uint64(calcMaxToBPV(max))
+		if size := int64(marshalHeaderSize + 37*bpv + 4); n != size || int64(buf.Len()) != size {
+			t.Errorf("max %d: WriteTo: expected %d bytes, got %d (%d written)", max, size, n, buf.Len())
+		}
+		raw := append([]byte(nil), buf.Bytes()...)
+
+		info, err := ReadInfo(bytes.NewReader(raw[0:marshalHeaderSize]))
+		if expect := (Info{Version: "1", BytesPerValue: uint8(bpv), MaxValue: max, Len: 37}); err != nil || info != expect {
+			t.Errorf("max %d: ReadInfo: expected %+v, got %+v, %v", max, expect, info, err)
+		}
+		dup, err := ReadFrom(&buf, opts...)
+		if err != nil {
+			t.Errorf("max %d: ReadFrom: error: %v", max, err)
+		} else {
+			if actual := dup.Debug(); actual != expect {
+				t.Errorf("max %d: ReadFrom: expected %s, got %s", max, expect, actual)
+			}
+			if dup.MaxValue() != max {
+				t.Errorf("max %d: ReadFrom: expected MaxValue %d, got %d", max, max, dup.MaxValue())
+			}
+			dup.Close()
+		}
+
+		raw[marshalHeaderSize] ^= 0x01
+		if _, err := ReadFrom(bytes.NewReader(raw), opts...); err != ErrChecksumMismatch {
+			t.Errorf("max %d: ReadFrom: expected ErrChecksumMismatch, got %v", max, err)
+		}
+		if _, err := ReadFrom(bytes.NewReader(raw[0:30]), opts...); err != io.ErrUnexpectedEOF {
+			t.Errorf("max %d: ReadFrom: expected io.ErrUnexpectedEOF, got %v", max, err)
+		}
+
+		data, err := ba.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			t.Errorf("max %d: MarshalBinary: error: %v", max, err)
+		}
+		small, err := New(MaxValue(max), NumValues(1))
+		if err != nil {
+			t.Errorf("New: error: %v", err)
+			return
+		}
+		if err := small.(encoding.BinaryUnmarshaler).UnmarshalBinary(data); err != nil {
+			t.Errorf("max %d: UnmarshalBinary: error: %v", max, err)
+		} else if actual := small.Debug(); actual != expect {
+			t.Errorf("max %d: UnmarshalBinary: expected %s, got %s", max, expect, actual)
+		}
+		ba.Close()
+	}
+
+	big, err := New(MaxValue(1000), NumValues(2))
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	big.SetValueAt(1, 999)
+	data, err := big.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		t.Errorf("MarshalBinary: error: %v", err)
+	}
+	narrow, err := New(MaxValue(100), NumValues(5))
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	err = narrow.(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+	if _, ok := err.(*OutOfRangeError); !ok {
+		t.Errorf("UnmarshalBinary: expected *OutOfRangeError, got %v", err)
+	}
+	if narrow.Len() != 5 {
+		t.Errorf("UnmarshalBinary: expected array to be unchanged, got %s", narrow.Debug())
+	}
+}
+
+func TestMarshal_InMemory(t *testing.T) {
+	RunMarshalTests(t)
+}
+
+func TestMarshal_OnDisk(t *testing.T) {
+	RunMarshalTests(t, OnDiskThreshold(0))
+}
+
+func RunMarshalWrapperTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	ba, err := New(append(opts, MaxValue(200), PageSize(16), NumValues(20))...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+	iter := ba.Iterate(0, ba.Len())
+	iter.Next()
+	iter.SetValue(5)
+	if _, err := ba.(io.WriterTo).WriteTo(ioutil.Discard); err != nil {
+		t.Errorf("WriteTo with a live Iterator: error: %v", err)
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("Iterator.Close: error: %v", err)
+	}
+
+	nba, err := New(append(opts, Nullable(), MaxValue(60000), PageSize(16), NumValues(20))...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer nba.Close()
+	for i := uint64(0); i < nba.Len(); i += 3 {
+		nba.SetValueAt(i, i*1000)
+	}
+	expect := nba.Debug()
+
+	var buf bytes.Buffer
+	if _, err := nba.(io.WriterTo).WriteTo(&buf); err != nil {
+		t.Errorf("Nullable: WriteTo: error: %v", err)
+	}
+	raw := append([]byte(nil), buf.Bytes()...)
+	dup, err := ReadFrom(&buf, opts...)
+	if err != nil {
+		t.Errorf("Nullable: ReadFrom: error: %v", err)
+	} else {
+		if info, err := ReadInfo(bytes.NewReader(raw)); err != nil || !info.Nullable {
+			t.Errorf("Nullable: ReadInfo: expected Nullable, got %+v, %v", info, err)
+		}
+		if _, ok := dup.(NullableArray); !ok {
+			t.Errorf("Nullable: ReadFrom: expected NullableArray, got %T", dup)
+		}
+		if actual := dup.Debug(); actual != expect {
+			t.Errorf("Nullable: ReadFrom: expected %s, got %s", expect, actual)
+		}
+		dup.Close()
+	}
+
+	raw[len(raw)-5] ^= 0x01
+	if _, err := ReadFrom(bytes.NewReader(raw), opts...); err != ErrChecksumMismatch {
+		t.Errorf("Nullable: ReadFrom: expected ErrChecksumMismatch, got %v", err)
+	}
+
+	data, err := nba.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		t.Errorf("Nullable: MarshalBinary: error: %v", err)
+	}
+	small, err := New(append(opts, Nullable(), MaxValue(60000), NumValues(1))...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer small.Close()
+	if err := small.(encoding.BinaryUnmarshaler).UnmarshalBinary(data); err != nil {
+		t.Errorf("Nullable: UnmarshalBinary: error: %v", err)
+	} else if actual := small.Debug(); actual != expect {
+		t.Errorf("Nullable: UnmarshalBinary: expected %s, got %s", expect, actual)
+	}
+
+	wide, err := New(MaxValue(1<<20), NumValues(3))
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer wide.Close()
+	wide.SetValueAt(2, 1<<20)
+	data, err = wide.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		t.Errorf("MarshalBinary: error: %v", err)
+	}
+	dyn, err := New(append(opts, AutoWiden(), MaxValue(255), NumValues(1))...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer dyn.Close()
+	if err := dyn.(encoding.BinaryUnmarshaler).UnmarshalBinary(data); err != nil {
+		t.Errorf("AutoWiden: UnmarshalBinary: error: %v", err)
+	} else if expect, actual := wide.Debug(), dyn.Debug(); actual != expect {
+		t.Errorf("AutoWiden: UnmarshalBinary: expected %s, got %s", expect, actual)
+	}
+	if _, err := dyn.(io.WriterTo).WriteTo(ioutil.Discard); err != nil {
+		t.Errorf("AutoWiden: WriteTo: error: %v", err)
+	}
+}
+
+func TestMarshalWrappers_InMemory(t *testing.T) {
+	RunMarshalWrapperTests(t)
+}
+
+func TestMarshalWrappers_OnDisk(t *testing.T) {
+	RunMarshalWrapperTests(t, OnDiskThreshold(0))
+}
@@ -0,0 +1,304 @@
+package bigarray
+
+import (
+	"fmt"
+	"io"
+)
+
+// maxTransposeTile is the upper bound on the number of elements which
+// Matrix.Transpose will hold in memory at once.
+const maxTransposeTile = 1 << 20
+
+// Layout describes how the elements of a Matrix are arranged in its backing
+// BigArray.
+type Layout byte
+
+const (
+	// RowMajor stores each row contiguously.
+	RowMajor Layout = iota
+
+	// ColumnMajor stores each column contiguously.
+	ColumnMajor
+)
+
+// String returns a human-friendly name for the layout.
+func (layout Layout) String() string {
+	switch layout {
+	case RowMajor:
+		return "RowMajor"
+	case ColumnMajor:
+		return "ColumnMajor"
+	default:
+		return fmt.Sprintf("Layout(%d)", byte(layout))
+	}
+}
+
+// Matrix is a dense two-dimensional view over a BigArray.
+type Matrix struct {
+	ba     BigArray
+	rows   uint64
+	cols   uint64
+	layout Layout
+}
+
+// NewMatrix constructs a Matrix with the given dimensions and layout.
+//
+// The options are interpreted as for New, except that NumValues is computed
+// from the dimensions.
+func NewMatrix(rows, cols uint64, layout Layout, opts ...Option) (*Matrix, error) {
+	opts = append(opts, NumValues(rows*cols))
+	ba, err := New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return AsMatrix(ba, rows, cols, layout), nil
+}
+
+// AsMatrix interprets an existing BigArray as a Matrix.  The array's length
+// must be exactly rows*cols.
+func AsMatrix(ba BigArray, rows, cols uint64, layout Layout) *Matrix {
+	if layout != RowMajor && layout != ColumnMajor {
+		panic(fmt.Errorf("unknown layout %v", layout))
+	}
+	if rows*cols != ba.Len() {
+		panic(fmt.Errorf("matrix of %dx%d does not match array of length %d", rows, cols, ba.Len()))
+	}
+	return &Matrix{ba: ba, rows: rows, cols: cols, layout: layout}
+}
+
+// Rows returns the number of rows in the matrix.
+func (m *Matrix) Rows() uint64 {
+	return m.rows
+}
+
+// Cols returns the number of columns in the matrix.
+func (m *Matrix) Cols() uint64 {
+	return m.cols
+}
+
+// Layout returns the arrangement of the matrix's elements.
+func (m *Matrix) Layout() Layout {
+	return m.layout
+}
+
+// Array returns the BigArray which backs the matrix.
+func (m *Matrix) Array() BigArray {
+	return m.ba
+}
+
+func (m *Matrix) index(r, c uint64) uint64 {
+	if m.layout == ColumnMajor {
+		return c*m.rows + r
+	}
+	return r*m.cols + c
+}
+
+// At returns the value at row (r), column (c).
+func (m *Matrix) At(r, c uint64) (uint64, error) {
+	if r >= m.rows || c >= m.cols {
+		return ^uint64(0), io.EOF
+	}
+	return m.ba.ValueAt(m.index(r, c))
+}
+
+// Set replaces the value at row (r), column (c).
+func (m *Matrix) Set(r, c, value uint64) error {
+	if r >= m.rows || c >= m.cols {
+		return io.EOF
+	}
+	return m.ba.SetValueAt(m.index(r, c), value)
+}
+
+// IterateRow returns an Iterator over the elements of row (r).  The
+// iterator's Index() is the column number.
+//
+// Rows of a ColumnMajor matrix are not contiguous, so iterating them is slow
+// for on-disk arrays.  Consider using Transpose first.
+func (m *Matrix) IterateRow(r uint64) Iterator {
+	if r >= m.rows {
+		panic(fmt.Errorf("Matrix.IterateRow: row out of range: r=%d rows=%d", r, m.rows))
+	}
+	if m.layout == RowMajor {
+		return newStridedIterator(m.ba, r*m.cols, 1, m.cols)
+	}
+	return newStridedIterator(m.ba, r, m.rows, m.cols)
+}
+
+// IterateCol returns an Iterator over the elements of column (c).  The
+// iterator's Index() is the row number.
+//
+// Columns of a RowMajor matrix are not contiguous, so iterating them is slow
+// for on-disk arrays.  Consider using Transpose first.
+func (m *Matrix) IterateCol(c uint64) Iterator {
+	if c >= m.cols {
+		panic(fmt.Errorf("Matrix.IterateCol: column out of range: c=%d cols=%d", c, m.cols))
+	}
+	if m.layout == ColumnMajor {
+		return newStridedIterator(m.ba, c*m.rows, 1, m.rows)
+	}
+	return newStridedIterator(m.ba, c, m.cols, m.rows)
+}
+
+// Transpose constructs a new matrix which is the transpose of this one, with
+// the same layout.
+//
+// The options are interpreted as for New, except that NumValues is computed
+// from the dimensions.  MaxValue defaults to this matrix's MaxValue.
+//
+// The copy is performed in square tiles sized to the array's PageSize, so
+// that both the source and the destination are accessed one page-sized run
+// at a time rather than one element at a time.
+func (m *Matrix) Transpose(opts ...Option) (*Matrix, error) {
+	opts = append([]Option{MaxValue(m.ba.MaxValue())}, opts...)
+
+	var o options
+	o.apply(opts...)
+	o.populate()
+
+	t, err := NewMatrix(m.cols, m.rows, m.layout, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	major, minor := m.rows, m.cols
+	if m.layout == ColumnMajor {
+		major, minor = m.cols, m.rows
+	}
+
+	tile := uint64(o.pageSize / uint(o.bytesPerValue))
+	for tile > 1 && tile*tile > maxTransposeTile {
+		tile /= 2
+	}
+
+	err = transposeImpl(t.ba, m.ba, major, minor, tile)
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// Close closes the BigArray which backs the matrix.
+func (m *Matrix) Close() error {
+	return m.ba.Close()
+}
+
+// Debug generates a human-friendly string representing the values in the
+// matrix, one row per line.
+func (m *Matrix) Debug() string {
+	var buf []byte
+	for r := uint64(0); r < m.rows; r++ {
+		buf = append(buf, '[')
+		for c := uint64(0); c < m.cols; c++ {
+			if c > 0 {
+				buf = append(buf, ' ')
+			}
+			value, err := m.At(r, c)
+			if err != nil {
+				buf = append(buf, '!')
+				continue
+			}
+			buf = append(buf, fmt.Sprintf("%d", value)...)
+		}
+		buf = append(buf, ']', '\n')
+	}
+	return string(buf)
+}
+
+// transposeImpl treats src as a (major x minor) grid stored contiguously by
+// major index, and writes its (minor x major) transpose into dst.
+func transposeImpl(dst, src BigArray, major, minor, tile uint64) error {
+	buf := make([]uint64, tile*tile)
+	for p0 := uint64(0); p0 < major; p0 += tile {
+		p1 := p0 + tile
+		if p1 > major {
+			p1 = major
+		}
+		for q0 := uint64(0); q0 < minor; q0 += tile {
+			q1 := q0 + tile
+			if q1 > minor {
+				q1 = minor
+			}
+
+			for p := p0; p < p1; p++ {
+				row := buf[(p-p0)*tile:]
+				iter := src.Iterate(p*minor+q0, p*minor+q1)
+				for iter.Next() {
+					row[iter.Index()-p*minor-q0] = iter.Value()
+				}
+				if err := iter.Close(); err != nil {
+					return err
+				}
+			}
+
+			for q := q0; q < q1; q++ {
+				iter := dst.Iterate(q*major+p0, q*major+p1)
+				for iter.Next() {
+					p := iter.Index() - q*major
+					iter.SetValue(buf[(p-p0)*tile+(q-q0)])
+				}
+				if err := iter.Close(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// stridedIterator visits (num) elements of an array, starting at index (base)
+// and advancing by (stride) elements each step.
+type stridedIterator struct {
+	iter   Iterator
+	base   uint64
+	stride uint64
+	num    uint64
+	primed bool
+}
+
+func newStridedIterator(ba BigArray, base, stride, num uint64) *stridedIterator {
+	end := base
+	if num > 0 {
+		end = base + (num-1)*stride + 1
+	}
+	return &stridedIterator{
+		iter:   ba.Iterate(base, end),
+		base:   base,
+		stride: stride,
+		num:    num,
+	}
+}
+
+func (iter *stridedIterator) Err() error            { return iter.iter.Err() }
+func (iter *stridedIterator) Next() bool            { return iter.Skip(1) }
+func (iter *stridedIterator) Value() uint64         { return iter.iter.Value() }
+func (iter *stridedIterator) Valid() bool           { return iter.iter.Valid() }
+func (iter *stridedIterator) SetValue(value uint64) { iter.iter.SetValue(value) }
+func (iter *stridedIterator) Flush() error          { return iter.iter.Flush() }
+func (iter *stridedIterator) Close() error          { return iter.iter.Close() }
+
+func (iter *stridedIterator) Index() uint64 {
+	return (iter.iter.Index() - iter.base) / iter.stride
+}
+
+func (iter *stridedIterator) Skip(n uint64) bool {
+	if n == 0 && !iter.primed {
+		panic(fmt.Errorf("must call Next() before Skip(0)"))
+	}
+	if n == 0 {
+		return iter.iter.Skip(0)
+	}
+	if !iter.primed {
+		iter.primed = true
+		if !iter.iter.Next() {
+			return false
+		}
+		n--
+		if n == 0 {
+			return true
+		}
+	}
+	return iter.iter.Skip(n * iter.stride)
+}
+
+var _ Iterator = (*stridedIterator)(nil)
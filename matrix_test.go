@@ -0,0 +1,91 @@
+package bigarray
+
+import (
+	"testing"
+)
+
+func RunMatrixBasicTests(t *testing.T, layout Layout, opts ...Option) {
+	t.Helper()
+
+	opts = append(opts,
+		MaxValue(1000),
+		PageSize(8))
+
+	m, err := NewMatrix(5, 7, layout, opts...)
+	if err != nil {
+		t.Errorf("NewMatrix: error: %v", err)
+		return
+	}
+	defer m.Close()
+
+	for r := uint64(0); r < m.Rows(); r++ {
+		for c := uint64(0); c < m.Cols(); c++ {
+			if err := m.Set(r, c, r*10+c); err != nil {
+				t.Errorf("Matrix.Set %d,%d: error: %v", r, c, err)
+			}
+		}
+	}
+
+	iter := m.IterateRow(3)
+	n := uint64(0)
+	for iter.Next() {
+		if iter.Index() != n || iter.Value() != 30+n {
+			t.Errorf("Matrix.IterateRow: expected [%d]=%d, got [%d]=%d", n, 30+n, iter.Index(), iter.Value())
+		}
+		n++
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("Matrix.IterateRow: error: %v", err)
+	}
+	if n != m.Cols() {
+		t.Errorf("Matrix.IterateRow only produced %d values", n)
+	}
+
+	iter = m.IterateCol(6)
+	n = 0
+	for iter.Next() {
+		if iter.Index() != n || iter.Value() != n*10+6 {
+			t.Errorf("Matrix.IterateCol: expected [%d]=%d, got [%d]=%d", n, n*10+6, iter.Index(), iter.Value())
+		}
+		n++
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("Matrix.IterateCol: error: %v", err)
+	}
+	if n != m.Rows() {
+		t.Errorf("Matrix.IterateCol only produced %d values", n)
+	}
+
+	mt, err := m.Transpose(opts...)
+	if err != nil {
+		t.Errorf("Matrix.Transpose: error: %v", err)
+		return
+	}
+	defer mt.Close()
+
+	if mt.Rows() != 7 || mt.Cols() != 5 || mt.Layout() != layout {
+		t.Errorf("Matrix.Transpose: wrong shape %dx%d %v", mt.Rows(), mt.Cols(), mt.Layout())
+	}
+	for r := uint64(0); r < mt.Rows(); r++ {
+		for c := uint64(0); c < mt.Cols(); c++ {
+			value, err := mt.At(r, c)
+			if err != nil {
+				t.Errorf("Matrix.At %d,%d: error: %v", r, c, err)
+				continue
+			}
+			if value != c*10+r {
+				t.Errorf("Matrix.At %d,%d: expected %d, got %d", r, c, c*10+r, value)
+			}
+		}
+	}
+}
+
+func TestMatrix_InMemory(t *testing.T) {
+	RunMatrixBasicTests(t, RowMajor)
+	RunMatrixBasicTests(t, ColumnMajor)
+}
+
+func TestMatrix_OnDisk(t *testing.T) {
+	RunMatrixBasicTests(t, RowMajor, OnDiskThreshold(0))
+	RunMatrixBasicTests(t, ColumnMajor, OnDiskThreshold(0))
+}
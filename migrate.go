@@ -0,0 +1,120 @@
+package bigarray
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrNotTemporary is returned when asked to move an array out of a file
+// which was provided by the caller, rather than created by this package.
+var ErrNotTemporary = errors.New("array is not backed by a temporary file")
+
+// MigratableArray is a BigArray which can be moved between memory and disk
+// after it has been created, without disturbing the caller's handle to it.
+//
+// Arrays created with Migratable, AutoMigrate, AutoWiden, or WithMemoryBudget
+// implement MigratableArray, including nullable ones, whose nulls move along
+// with their values.  Outstanding iterators are migrated along with
+// the array and continue from the same position.
+type MigratableArray interface {
+	BigArray
+
+	// InMemory returns true if the array is currently held in memory.
+	InMemory() bool
+
+	// Promote moves the array from its temporary file into memory, and
+	// deletes the file.  It returns ErrNotTemporary if the array is backed
+	// by a file from WithFile or WithReadOnlyFile.  Promote ignores the
+	// OnDiskThreshold and the MemoryBudget; the bytes are charged to the
+	// budget regardless.
+	Promote() error
+
+	// Demote moves the array from memory into a new temporary file.
+	Demote() error
+}
+
+func (ba *dynamicArray) InMemory() bool {
+	ba.lock()
+	defer ba.unlock()
+	return isInMemory(ba.impl)
+}
+
+func (ba *dynamicArray) Promote() error {
+	ba.lock()
+	defer ba.unlock()
+	x, ok := ba.impl.(*onDiskArray)
+	if !ok {
+		return nil
+	}
+	if !x.doc {
+		return ErrNotTemporary
+	}
+	if mb := ba.o.budget; mb != nil {
+		atomic.StoreUint64(&ba.lastUse, mb.tick())
+	}
+	return ba.relocate(true)
+}
+
+func (ba *dynamicArray) Demote() error {
+	ba.lock()
+	defer ba.unlock()
+	return ba.demote()
+}
+
+// demote is Demote for callers which already hold the lock.
+func (ba *dynamicArray) demote() error {
+	if !isInMemory(ba.impl) {
+		atomic.StoreInt32(&ba.spill, 0)
+		return nil
+	}
+	return ba.relocate(false)
+}
+
+// relocate moves the array into memory or into a temporary file, and updates
+// the MemoryBudget to match.  An array marked to be spilled stays marked until
+// it has moved, so that the budget does not pick another array to make room
+// for the pages used by the move.
+func (ba *dynamicArray) relocate(inMemory bool) error {
+	err := ba.replace(func(old BigArray) (BigArray, error) {
+		return relocateImpl(old, ba.o, inMemory)
+	})
+	atomic.StoreInt32(&ba.spill, 0)
+	if err != nil {
+		return err
+	}
+	if mb := ba.o.budget; mb != nil {
+		mb.adjust(ba, ba.heldBytes())
+	}
+	return nil
+}
+
+// resized is called after the length of the array has changed.  It updates
+// the MemoryBudget and, with AutoMigrate, moves the array if its new size has
+// crossed the OnDiskThreshold.
+func (ba *dynamicArray) resized() error {
+	if ba.o.autoMigrate {
+		numBytes := ba.impl.Len() * uint64(bytesPerValueOf(ba.impl))
+		x, onDisk := ba.impl.(*onDiskArray)
+		switch {
+		case !onDisk && numBytes >= ba.o.diskThreshold:
+			return ba.relocate(false)
+		case onDisk && x.doc && numBytes < ba.o.diskThreshold && ba.o.budget.fits(numBytes):
+			return ba.relocate(true)
+		}
+	}
+	if mb := ba.o.budget; mb != nil {
+		mb.adjust(ba, ba.heldBytes())
+	}
+	return nil
+}
+
+// resizedImpl notifies (ba) that its length has changed, if it needs to know.
+// The caller holds the lock taken by lockImpl.
+func resizedImpl(ba BigArray) error {
+	if x, ok := ba.(*dynamicArray); ok {
+		return x.resized()
+	}
+	return nil
+}
+
+var _ MigratableArray = (*dynamicArray)(nil)
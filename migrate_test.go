@@ -0,0 +1,200 @@
+package bigarray
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func RunMigrateTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	opts = append(opts,
+		Migratable(),
+		MaxValue(1000),
+		PageSize(16),
+		NumValues(40))
+
+	ba, err := New(opts...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+	for i := uint64(0); i < ba.Len(); i++ {
+		ba.SetValueAt(i, i*10)
+	}
+	expect := ba.Debug()
+
+	ma, ok := ba.(MigratableArray)
+	if !ok {
+		t.Errorf("New: expected a MigratableArray, got %T", ba)
+		return
+	}
+
+	iter := ba.Iterate(0, ba.Len())
+	if !iter.Skip(5) {
+		t.Errorf("Iterator.Skip: error: %v", iter.Err())
+	}
+
+	if err := ma.Demote(); err != nil {
+		t.Errorf("MigratableArray.Demote: error: %v", err)
+	}
+	if ma.InMemory() {
+		t.Errorf("MigratableArray.Demote: still in memory")
+	}
+	if actual := ba.Debug(); actual != expect {
+		t.Errorf("MigratableArray.Demote: expected %s, got %s", expect, actual)
+	}
+
+	if err := ma.Promote(); err != nil {
+		t.Errorf("MigratableArray.Promote: error: %v", err)
+	}
+	if !ma.InMemory() {
+		t.Errorf("MigratableArray.Promote: still on disk")
+	}
+	if actual := ba.Debug(); actual != expect {
+		t.Errorf("MigratableArray.Promote: expected %s, got %s", expect, actual)
+	}
+
+	if iter.Index() != 4 || !iter.Next() || iter.Value() != 50 {
+		t.Errorf("migrated Iterator: expected [5]=50, got err=%v", iter.Err())
+	}
+	iter.SetValue(999)
+	if err := iter.Close(); err != nil {
+		t.Errorf("migrated Iterator.Close: error: %v", err)
+	}
+	if value, _ := ba.ValueAt(5); value != 999 {
+		t.Errorf("BigArray.ValueAt 5: expected 999, got %d", value)
+	}
+}
+
+func TestMigrate_InMemory(t *testing.T) {
+	RunMigrateTests(t)
+}
+
+func TestMigrate_OnDisk(t *testing.T) {
+	RunMigrateTests(t, OnDiskThreshold(0))
+}
+
+func TestMigrate_Auto(t *testing.T) {
+	ba, err := New(
+		AutoMigrate(),
+		MaxValue(255),
+		PageSize(16),
+		NumValues(100),
+		OnDiskThreshold(64))
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+	ma := ba.(MigratableArray)
+	if ma.InMemory() {
+		t.Errorf("New: expected the array to start on disk")
+	}
+	for i := uint64(0); i < ba.Len(); i++ {
+		ba.SetValueAt(i, i)
+	}
+
+	if err := ba.Truncate(50); err != nil {
+		t.Errorf("BigArray.Truncate: error: %v", err)
+	}
+	if !ma.InMemory() {
+		t.Errorf("BigArray.Truncate: expected the array to move to memory")
+	}
+
+	if err := InsertAt(ba, 0, make([]uint64, 20)...); err != nil {
+		t.Errorf("InsertAt: error: %v", err)
+	}
+	if ma.InMemory() {
+		t.Errorf("InsertAt: expected the array to move to disk")
+	}
+	if value, _ := ba.ValueAt(69); value != 49 {
+		t.Errorf("BigArray.ValueAt 69: expected 49, got %d", value)
+	}
+
+	if err := DropPrefix(ba, 30); err != nil {
+		t.Errorf("DropPrefix: error: %v", err)
+	}
+	if !ma.InMemory() {
+		t.Errorf("DropPrefix: expected the array to move to memory")
+	}
+	if value, _ := ba.ValueAt(0); value != 10 {
+		t.Errorf("BigArray.ValueAt 0: expected 10, got %d", value)
+	}
+}
+
+func TestMigrate_Nullable(t *testing.T) {
+	ba, err := New(Nullable(), Migratable(), MaxValue(255), PageSize(16), NumValues(20))
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+	ma, ok := ba.(MigratableArray)
+	if !ok {
+		t.Errorf("New: expected MigratableArray, got %T", ba)
+		return
+	}
+	if _, ok := ba.(NullableArray); !ok {
+		t.Errorf("New: expected NullableArray, got %T", ba)
+	}
+	for i := uint64(0); i < ba.Len(); i += 3 {
+		ba.SetValueAt(i, i)
+	}
+	expect := ba.Debug()
+
+	if err := ma.Demote(); err != nil {
+		t.Errorf("MigratableArray.Demote: error: %v", err)
+	}
+	if ma.InMemory() {
+		t.Errorf("MigratableArray.Demote: expected the array to move to disk")
+	}
+	if actual := ba.Debug(); actual != expect {
+		t.Errorf("MigratableArray.Demote: expected %s, got %s", expect, actual)
+	}
+
+	if err := DropPrefix(ba, 3); err != nil {
+		t.Errorf("DropPrefix: error: %v", err)
+	}
+	if err := ma.Promote(); err != nil {
+		t.Errorf("MigratableArray.Promote: error: %v", err)
+	}
+	if !ma.InMemory() {
+		t.Errorf("MigratableArray.Promote: expected the array to move to memory")
+	}
+	const expectDropped = "[3 . . 6 . . 9 . . 12 . . 15 . . 18 .]"
+	if actual := ba.Debug(); actual != expectDropped {
+		t.Errorf("MigratableArray.Promote: expected %s, got %s", expectDropped, actual)
+	}
+
+	plain, err := New(Nullable(), MaxValue(255), NumValues(4))
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer plain.Close()
+	if _, ok := plain.(MigratableArray); ok {
+		t.Errorf("New: expected a nullable array without Migratable not to be a MigratableArray")
+	}
+}
+
+func TestMigrate_WithFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "migrate")
+	if err != nil {
+		t.Errorf("TempFile: error: %v", err)
+		return
+	}
+	defer os.Remove(f.Name())
+
+	ba, err := New(Migratable(), MaxValue(255), NumValues(16), WithFile(f))
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+	if err := ba.(MigratableArray).Promote(); err != ErrNotTemporary {
+		t.Errorf("MigratableArray.Promote: expected ErrNotTemporary, got %v", err)
+	}
+}
@@ -79,6 +79,9 @@ func RunBigArrayBasicTests(t *testing.T, opts ...Option) {
 			t.Errorf("BigArray.Iterate out of order: expected [%d], got [%d]", n, index)
 		}
 		iter.SetValue(index)
+		if iter.Value() != index {
+			t.Errorf("Iterator.Value after SetValue [%d]: expected %d, got %d", index, index, iter.Value())
+		}
 		n++
 	}
 	if err := iter.Close(); err != nil {
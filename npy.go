@@ -0,0 +1,277 @@
+package bigarray
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// npyMagic is the magic string which begins every .npy file.
+const npyMagic = "\x93NUMPY"
+
+// npyAlign is the alignment of the data which follows a .npy header.
+const npyAlign = 64
+
+var (
+	npyDescrRE   = regexp.MustCompile(`'descr'\s*:\s*'([^']*)'`)
+	npyFortranRE = regexp.MustCompile(`'fortran_order'\s*:\s*(True|False)`)
+	npyShapeRE   = regexp.MustCompile(`'shape'\s*:\s*\(\s*(\d+)\s*,?\s*\)`)
+)
+
+// WriteNPY writes the array to (w) in NumPy's .npy format, as a 1-D array
+// with dtype <u1, <u2, <u4, or <u8.  The dtype is the smallest which can hold
+// ba.MaxValue().
+//
+// A version 1.0 header is written unless the header is too long for one, in
+// which case a version 2.0 header is written.  The values are streamed from
+// an Iterator, so on-disk arrays are written one page at a time.
+//
+func WriteNPY(w io.Writer, ba BigArray) error {
+	bpv := calcMaxToBPV(ba.MaxValue())
+	header := npyHeader(bpv, ba.Len())
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	var tmp [8]byte
+	iter := ba.Iterate(0, ba.Len())
+	for iter.Next() {
+		bpvEncode(bpv, tmp[0:bpv], iter.Value())
+		if _, err := bw.Write(tmp[0:bpv]); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ReadNPY constructs a new array from a .npy file read from (r).
+//
+// The file must hold a 1-D array of unsigned little-endian integers.
+// BytesPerValue and NumValues are taken from the header.  If MaxValue is not
+// specified, it defaults to the largest value representable by the dtype; if
+// it is specified and a value exceeds it, an *OutOfRangeError is returned.
+// Whether the new array is in-memory or on-disk is decided by
+// OnDiskThreshold, as for New.
+//
+func ReadNPY(r io.Reader, opts ...Option) (BigArray, error) {
+	br := bufio.NewReader(r)
+	bpv, num, _, err := readNPYHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	var o options
+	o.apply(opts...)
+	o.numValues = num
+	o.bytesPerValue = bpv
+	o.populate()
+
+	ba, err := build(o)
+	if err != nil {
+		return nil, err
+	}
+
+	var tmp [8]byte
+	iter := ba.Iterate(0, num)
+	for iter.Next() {
+		if _, err := io.ReadFull(br, tmp[0:bpv]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			iter.Close()
+			ba.Close()
+			return nil, err
+		}
+		value := bpvDecode(bpv, tmp[0:bpv])
+		if value > o.maxValue {
+			index := iter.Index()
+			iter.Close()
+			ba.Close()
+			return nil, &OutOfRangeError{Index: index, Value: value, Max: o.maxValue}
+		}
+		iter.SetValue(value)
+	}
+	if err := iter.Close(); err != nil {
+		ba.Close()
+		return nil, err
+	}
+	return ba, nil
+}
+
+// OpenNPY wraps an existing .npy file as an on-disk array, without copying
+// the data.  The file is provided with WithFile or WithReadOnlyFile.
+//
+// The header is parsed as for ReadNPY, and element 0 of the array is the
+// first byte after the header.  The header is never rewritten, so Truncate
+// leaves the file's recorded shape unchanged.
+//
+func OpenNPY(opts ...Option) (BigArray, error) {
+	var o options
+	o.apply(opts...)
+	if o.backingFile == nil {
+		panic(errors.New("must specify WithFile or WithReadOnlyFile"))
+	}
+
+	sr := io.NewSectionReader(o.backingFile, 0, 1<<62)
+	bpv, num, base, err := readNPYHeader(bufio.NewReader(sr))
+	if err != nil {
+		return nil, err
+	}
+
+	o.numValues = num
+	o.bytesPerValue = bpv
+	o.populate()
+
+	ba := &onDiskArray{
+		f:     o.backingFile,
+		p:     o.bufferPool,
+		cache: make(map[uint64]*cachePage),
+		st:    newIOStats(o.observer),
+		mb:    o.budget,
+		dur:   newDurability(o, false),
+		base:  base,
+		num:   o.numValues,
+		max:   o.maxValue,
+		psz:   o.pageSize,
+		bpv:   o.bytesPerValue,
+		ro:    o.isReadOnly,
+	}
+	return ba, nil
+}
+
+// npyHeader returns the complete header, including the magic string, for a
+// 1-D array of (num) values of (bpv) bytes each.
+func npyHeader(bpv byte, num uint64) []byte {
+	dict := fmt.Sprintf("{'descr': '<u%d', 'fortran_order': False, 'shape': (%d,), }", bpv, num)
+
+	align := func(n int) int {
+		return ((n + npyAlign - 1) / npyAlign) * npyAlign
+	}
+
+	prefix := 10
+	major := byte(1)
+	total := align(prefix + len(dict) + 1)
+	if total-prefix > 0xffff {
+		prefix = 12
+		major = 2
+		total = align(prefix + len(dict) + 1)
+	}
+
+	header := make([]byte, total)
+	copy(header, npyMagic)
+	header[6] = major
+	header[7] = 0
+	if major == 1 {
+		binary.LittleEndian.PutUint16(header[8:], uint16(total-prefix))
+	} else {
+		binary.LittleEndian.PutUint32(header[8:], uint32(total-prefix))
+	}
+	n := copy(header[prefix:], dict)
+	for k := prefix + n; k < total-1; k++ {
+		header[k] = ' '
+	}
+	header[total-1] = '\n'
+	return header
+}
+
+// ReadNPYInfo reads the header of a .npy file.  The data is not read.  As for
+// ReadNPY, MaxValue is the largest value representable by the dtype.
+func ReadNPYInfo(r io.Reader) (Info, error) {
+	var head bytes.Buffer
+	bpv, num, _, err := readNPYHeader(io.TeeReader(r, &head))
+	if err != nil {
+		return Info{}, err
+	}
+	pre := head.Bytes()
+	info := Info{
+		Version:       fmt.Sprintf("%d.%d", pre[6], pre[7]),
+		BytesPerValue: bpv,
+		MaxValue:      calcBPVToMax(bpv),
+		Len:           num,
+	}
+	return info, nil
+}
+
+// readNPYHeader parses a .npy header, returning the BytesPerValue and length
+// of the array and the total size (bytes) of the header.
+func readNPYHeader(r io.Reader) (byte, uint64, uint64, error) {
+	var pre [8]byte
+	if _, err := io.ReadFull(r, pre[:]); err != nil {
+		return 0, 0, 0, err
+	}
+	if string(pre[0:6]) != npyMagic {
+		return 0, 0, 0, errors.New("not a .npy file: bad magic")
+	}
+
+	var size, prefix uint64
+	switch pre[6] {
+	case 1:
+		var tmp [2]byte
+		if _, err := io.ReadFull(r, tmp[:]); err != nil {
+			return 0, 0, 0, err
+		}
+		size = uint64(binary.LittleEndian.Uint16(tmp[:]))
+		prefix = 10
+	case 2, 3:
+		var tmp [4]byte
+		if _, err := io.ReadFull(r, tmp[:]); err != nil {
+			return 0, 0, 0, err
+		}
+		size = uint64(binary.LittleEndian.Uint32(tmp[:]))
+		prefix = 12
+	default:
+		return 0, 0, 0, fmt.Errorf("unsupported .npy version %d.%d", pre[6], pre[7])
+	}
+
+	dict := make([]byte, size)
+	if _, err := io.ReadFull(r, dict); err != nil {
+		return 0, 0, 0, err
+	}
+
+	m := npyDescrRE.FindSubmatch(dict)
+	if m == nil {
+		return 0, 0, 0, errors.New("malformed .npy header: missing descr")
+	}
+	var bpv byte
+	switch string(m[1]) {
+	case "|u1", "<u1":
+		bpv = 1
+	case "<u2":
+		bpv = 2
+	case "<u4":
+		bpv = 4
+	case "<u8":
+		bpv = 8
+	default:
+		return 0, 0, 0, fmt.Errorf("unsupported .npy dtype %q", m[1])
+	}
+
+	m = npyFortranRE.FindSubmatch(dict)
+	if m == nil {
+		return 0, 0, 0, errors.New("malformed .npy header: missing fortran_order")
+	}
+
+	m = npyShapeRE.FindSubmatch(dict)
+	if m == nil {
+		if bytes.Contains(dict, []byte("'shape'")) {
+			return 0, 0, 0, errors.New("unsupported .npy shape: must be 1-D")
+		}
+		return 0, 0, 0, errors.New("malformed .npy header: missing shape")
+	}
+	num, err := strconv.ParseUint(string(m[1]), 10, 64)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("malformed .npy header: %v", err)
+	}
+
+	return bpv, num, prefix + size, nil
+}
@@ -0,0 +1,123 @@
+package bigarray
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func RunNPYTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	ba, err := New(append(opts, MaxValue(1000), NumValues(5))...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+	for i := uint64(0); i < ba.Len(); i++ {
+		ba.SetValueAt(i, i*200)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteNPY(&buf, ba); err != nil {
+		t.Errorf("WriteNPY: error: %v", err)
+		return
+	}
+	raw := buf.Bytes()
+	if len(raw) != 128+10 {
+		t.Errorf("WriteNPY: expected %d bytes, got %d", 64+10, len(raw))
+	}
+	expectHeader := "\x93NUMPY\x01\x00\x76\x00{'descr': '<u2', 'fortran_order': False, 'shape': (5,), }"
+	if !bytes.HasPrefix(raw, []byte(expectHeader)) || raw[127] != '\n' {
+		t.Errorf("WriteNPY: unexpected header %q", raw[0:128])
+	}
+	info, err := ReadNPYInfo(bytes.NewReader(raw[0:128]))
+	if expect := (Info{Version: "1.0", BytesPerValue: 2, MaxValue: 0xffff, Len: 5}); err != nil || info != expect {
+		t.Errorf("ReadNPYInfo: expected %+v, got %+v, %v", expect, info, err)
+	}
+
+	dup, err := ReadNPY(bytes.NewReader(raw), opts...)
+	if err != nil {
+		t.Errorf("ReadNPY: error: %v", err)
+		return
+	}
+	defer dup.Close()
+	if expect, actual := "[0 200 400 600 800]", dup.Debug(); actual != expect {
+		t.Errorf("ReadNPY: expected %s, got %s", expect, actual)
+	}
+	if expect, actual := uint64(65535), dup.MaxValue(); actual != expect {
+		t.Errorf("ReadNPY: expected MaxValue %d, got %d", expect, actual)
+	}
+
+	_, err = ReadNPY(bytes.NewReader(raw), append(opts, MaxValue(500))...)
+	if _, ok := err.(*OutOfRangeError); !ok {
+		t.Errorf("ReadNPY: expected *OutOfRangeError, got %v", err)
+	}
+
+	_, err = ReadNPY(bytes.NewReader(raw[0:70]), opts...)
+	if err == nil {
+		t.Errorf("ReadNPY: expected error for short data, got nil")
+	}
+}
+
+func TestNPY_InMemory(t *testing.T) {
+	RunNPYTests(t)
+}
+
+func TestNPY_OnDisk(t *testing.T) {
+	RunNPYTests(t, OnDiskThreshold(0))
+}
+
+func TestNPY_Open(t *testing.T) {
+	src, err := New(MaxValue(1<<40), NumValues(10))
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer src.Close()
+	for i := uint64(0); i < src.Len(); i++ {
+		src.SetValueAt(i, i<<32)
+	}
+
+	f, err := ioutil.TempFile("", "npy")
+	if err != nil {
+		t.Errorf("TempFile: error: %v", err)
+		return
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if err := WriteNPY(f, src); err != nil {
+		t.Errorf("WriteNPY: error: %v", err)
+		return
+	}
+
+	ba, err := OpenNPY(WithFile(f), PageSize(16))
+	if err != nil {
+		t.Errorf("OpenNPY: error: %v", err)
+		return
+	}
+	if expect, actual := debugImpl(src), ba.Debug(); actual != expect {
+		t.Errorf("OpenNPY: expected %s, got %s", expect, actual)
+	}
+	if err := ba.SetValueAt(3, 7); err != nil {
+		t.Errorf("BigArray.SetValueAt: error: %v", err)
+	}
+	if err := ba.Flush(); err != nil {
+		t.Errorf("BigArray.Flush: error: %v", err)
+	}
+
+	ro, err := OpenNPY(WithReadOnlyFile(f))
+	if err != nil {
+		t.Errorf("OpenNPY: error: %v", err)
+		return
+	}
+	defer ro.Close()
+	if !ro.Frozen() {
+		t.Errorf("OpenNPY: expected frozen array")
+	}
+	if value, err := ro.ValueAt(3); err != nil || value != 7 {
+		t.Errorf("BigArray.ValueAt 3: expected 7, got %d, error: %v", value, err)
+	}
+}
@@ -0,0 +1,463 @@
+package bigarray
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// NullableArray is a BigArray in which each element may be null.
+//
+// Nullable arrays are created by passing the Nullable option to New.  Every
+// element starts out null, and becomes non-null when a value is assigned to
+// it.  ValueAt returns 0 for null elements; use IsNull, or Iterator.Valid, to
+// tell a null apart from a real zero.
+type NullableArray interface {
+	BigArray
+
+	// IsNull returns true if the element at the given index is null.
+	IsNull(uint64) (bool, error)
+
+	// SetNull marks the element at the given index as null.
+	SetNull(uint64) error
+}
+
+// nullableArray pairs a data array with a validity bitset.  Bit (i % 8) of
+// byte (i / 8) in the bitset is set if element (i) holds a value.
+type nullableArray struct {
+	data BigArray
+	bits BigArray
+	o    options
+}
+
+func newNullableArray(o options) (BigArray, error) {
+	if o.backingFile != nil {
+		panic(errors.New("Nullable does not support WithFile or WithReadOnlyFile"))
+	}
+	o.isNullable = false
+	data, err := build(o)
+	if err != nil {
+		return nil, err
+	}
+
+	bitsOpts := []Option{
+		NumValues((o.numValues + 7) / 8),
+		BytesPerValue(1),
+		OnDiskThreshold(o.diskThreshold),
+		PageSize(o.pageSize),
+		WithPool(o.bufferPool),
+		WithMemoryBudget(o.budget),
+		TempDir(o.tempDir),
+		TempPrefix(o.tempPrefix),
+	}
+	_, migratable := data.(MigratableArray)
+	if migratable {
+		bitsOpts = append(bitsOpts, Migratable())
+	}
+	if o.autoMigrate {
+		bitsOpts = append(bitsOpts, AutoMigrate())
+	}
+	bits, err := New(bitsOpts...)
+	if err != nil {
+		data.Close()
+		return nil, err
+	}
+
+	ba := &nullableArray{data: data, bits: bits, o: o}
+	if migratable {
+		return &migratableNullableArray{ba}, nil
+	}
+	return ba, nil
+}
+
+func (ba *nullableArray) Frozen() bool {
+	return ba.data.Frozen()
+}
+
+func (ba *nullableArray) MaxValue() uint64 {
+	return ba.data.MaxValue()
+}
+
+func (ba *nullableArray) Len() uint64 {
+	return ba.data.Len()
+}
+
+func (ba *nullableArray) IsNull(index uint64) (bool, error) {
+	if index >= ba.Len() {
+		return true, io.EOF
+	}
+	b, err := ba.bits.ValueAt(index / 8)
+	if err != nil {
+		return true, err
+	}
+	return (b & (1 << (index % 8))) == 0, nil
+}
+
+func (ba *nullableArray) SetNull(index uint64) error {
+	if ba.Frozen() {
+		panic("BigArray is read-only")
+	}
+	return ba.setValid(index, false)
+}
+
+func (ba *nullableArray) setValid(index uint64, valid bool) error {
+	if index >= ba.Len() {
+		return io.EOF
+	}
+	b, err := ba.bits.ValueAt(index / 8)
+	if err != nil {
+		return err
+	}
+	mask := uint64(1) << (index % 8)
+	if valid {
+		b |= mask
+	} else {
+		b &^= mask
+	}
+	return ba.bits.SetValueAt(index/8, b)
+}
+
+func (ba *nullableArray) ValueAt(index uint64) (uint64, error) {
+	null, err := ba.IsNull(index)
+	if err != nil {
+		return ^uint64(0), err
+	}
+	if null {
+		return 0, nil
+	}
+	return ba.data.ValueAt(index)
+}
+
+func (ba *nullableArray) SetValueAt(index uint64, value uint64) error {
+	if err := ba.data.SetValueAt(index, value); err != nil {
+		return err
+	}
+	return ba.setValid(index, true)
+}
+
+func (ba *nullableArray) Iterate(i, j uint64) Iterator {
+	if i > j {
+		panic(fmt.Errorf("nullableArray.Iterate: i > j: i=%d j=%d", i, j))
+	}
+	p, q := bitsRange(i, j)
+	return &nullableIterator{
+		data: ba.data.Iterate(i, j),
+		bits: ba.bits.Iterate(p, q),
+	}
+}
+
+func (ba *nullableArray) ReverseIterate(i, j uint64) Iterator {
+	if i > j {
+		panic(fmt.Errorf("nullableArray.ReverseIterate: i > j: i=%d j=%d", i, j))
+	}
+	p, q := bitsRange(i, j)
+	return &nullableIterator{
+		data: ba.data.ReverseIterate(i, j),
+		bits: ba.bits.ReverseIterate(p, q),
+		down: true,
+	}
+}
+
+func (ba *nullableArray) CopyFrom(src BigArray) error {
+	if ba.Frozen() {
+		panic("BigArray is read-only")
+	}
+	if src.Len() != ba.Len() {
+		panic("big arrays are not equal in size")
+	}
+	if x, ok := asNullable(src); ok {
+		if err := ba.data.CopyFrom(x.data); err != nil {
+			return err
+		}
+		return ba.bits.CopyFrom(x.bits)
+	}
+	if err := ba.data.CopyFrom(src); err != nil {
+		return err
+	}
+	_, nullable := src.(NullableArray)
+	return copyBits(ba.bits, src, nullable)
+}
+
+// copyBits rewrites a validity bitset for the elements of (src).  If
+// (nullable) is true, each bit is taken from Iterator.Valid; otherwise every
+// element is valid.  The padding bits past src.Len() are cleared.
+func copyBits(bits BigArray, src BigArray, nullable bool) error {
+	num := src.Len()
+	wr := bits.Iterate(0, bits.Len())
+	if !nullable {
+		for wr.Next() {
+			b := uint64(0xff)
+			if end := 8 * (wr.Index() + 1); end > num {
+				b >>= end - num
+			}
+			wr.SetValue(b)
+		}
+		return wr.Close()
+	}
+
+	rd := src.Iterate(0, num)
+	var b uint64
+	for rd.Next() {
+		index := rd.Index()
+		if rd.Valid() {
+			b |= 1 << (index % 8)
+		}
+		if index%8 == 7 || index == num-1 {
+			wr.Next()
+			wr.SetValue(b)
+			b = 0
+		}
+	}
+	err := rd.Close()
+	if err2 := wr.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (ba *nullableArray) Truncate(n uint64) error {
+	if err := ba.data.Truncate(n); err != nil {
+		return err
+	}
+	return ba.bits.Truncate((n + 7) / 8)
+}
+
+func (ba *nullableArray) Freeze() error {
+	if err := ba.data.Freeze(); err != nil {
+		return err
+	}
+	return ba.bits.Freeze()
+}
+
+func (ba *nullableArray) Flush() error {
+	if err := ba.data.Flush(); err != nil {
+		return err
+	}
+	return ba.bits.Flush()
+}
+
+func (ba *nullableArray) Sync() error {
+	if err := ba.data.Sync(); err != nil {
+		return err
+	}
+	return ba.bits.Sync()
+}
+
+func (ba *nullableArray) Close() error {
+	err := ba.data.Close()
+	if err2 := ba.bits.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (ba *nullableArray) Debug() string {
+	return debugImpl(ba)
+}
+
+// Stats returns the combined I/O counters of the values and the bitset.
+func (ba *nullableArray) Stats() Stats {
+	return StatsOf(ba.data).add(StatsOf(ba.bits))
+}
+
+// WriteTo writes the values followed by the validity bitset.
+func (ba *nullableArray) WriteTo(w io.Writer) (int64, error) {
+	data, unlock := lockImpl(ba.data)
+	bpv := bytesPerValueOf(data)
+	unlock()
+	h := marshalHeader{bpv: bpv, max: ba.MaxValue(), num: ba.Len(), nullable: true}
+	return writeToImpl(w, h, emitValues(ba.data, bpv), emitValues(ba.bits, 1))
+}
+
+func (ba *nullableArray) MarshalBinary() ([]byte, error) {
+	return marshalImpl(ba)
+}
+
+func (ba *nullableArray) UnmarshalBinary(data []byte) error {
+	if ba.Frozen() {
+		panic("BigArray is read-only")
+	}
+	o := ba.o
+	o.isNullable = true
+	old, unlock := lockImpl(ba.data)
+	dup, err := unmarshalImpl(data, o, old, build)
+	unlock()
+	if err != nil {
+		return err
+	}
+	x := unwrapArray(dup).(*nullableArray)
+	err = ba.Close()
+	ba.data, ba.bits = x.data, x.bits
+	return err
+}
+
+// asNullable returns the nullableArray behind (ba), if there is one.  A
+// nullableArray is never wrapped in a dynamicArray, so no lock is needed.
+func asNullable(ba BigArray) (*nullableArray, bool) {
+	switch x := ba.(type) {
+	case *nullableArray:
+		return x, true
+	case *migratableNullableArray:
+		return x.nullableArray, true
+	default:
+		return nil, false
+	}
+}
+
+var _ NullableArray = (*nullableArray)(nil)
+
+// migratableNullableArray is a nullableArray whose values and nulls can both
+// be moved between memory and disk.
+type migratableNullableArray struct {
+	*nullableArray
+}
+
+func (ba *migratableNullableArray) InMemory() bool {
+	return ba.data.(MigratableArray).InMemory() && ba.bits.(MigratableArray).InMemory()
+}
+
+func (ba *migratableNullableArray) Promote() error {
+	if err := ba.data.(MigratableArray).Promote(); err != nil {
+		return err
+	}
+	return ba.bits.(MigratableArray).Promote()
+}
+
+func (ba *migratableNullableArray) Demote() error {
+	if err := ba.data.(MigratableArray).Demote(); err != nil {
+		return err
+	}
+	return ba.bits.(MigratableArray).Demote()
+}
+
+var _ NullableArray = (*migratableNullableArray)(nil)
+var _ MigratableArray = (*migratableNullableArray)(nil)
+
+// bitsRange converts a range of element indices into the range of bitset
+// bytes which covers it.
+func bitsRange(i, j uint64) (uint64, uint64) {
+	if i == j {
+		return i / 8, i / 8
+	}
+	return i / 8, (j-1)/8 + 1
+}
+
+// nullSetter is implemented by the Iterators of nullable arrays and of views
+// over them, so that nulls can be written without leaving the Iterator.
+type nullSetter interface {
+	setNull()
+}
+
+type nullableIterator struct {
+	data   Iterator
+	bits   Iterator
+	err    error
+	cur    uint64
+	primed bool
+	down   bool
+}
+
+func (iter *nullableIterator) Next() bool { return iter.Skip(1) }
+
+func (iter *nullableIterator) Err() error {
+	if iter.err != nil {
+		return iter.err
+	}
+	return iter.data.Err()
+}
+
+func (iter *nullableIterator) Index() uint64 {
+	return iter.data.Index()
+}
+
+func (iter *nullableIterator) Valid() bool {
+	return (iter.cur & (1 << (iter.data.Index() % 8))) != 0
+}
+
+func (iter *nullableIterator) Value() uint64 {
+	if !iter.Valid() {
+		return 0
+	}
+	return iter.data.Value()
+}
+
+func (iter *nullableIterator) SetValue(value uint64) {
+	iter.data.SetValue(value)
+	mask := uint64(1) << (iter.data.Index() % 8)
+	if (iter.cur & mask) == 0 {
+		iter.cur |= mask
+		iter.bits.SetValue(iter.cur)
+	}
+}
+
+// setNull marks the current element as null, like NullableArray.SetNull.
+func (iter *nullableIterator) setNull() {
+	mask := uint64(1) << (iter.data.Index() % 8)
+	if (iter.cur & mask) != 0 {
+		iter.cur &^= mask
+		iter.bits.SetValue(iter.cur)
+	}
+}
+
+func (iter *nullableIterator) Skip(n uint64) bool {
+	if iter.err != nil {
+		return false
+	}
+	if !iter.data.Skip(n) {
+		return false
+	}
+
+	want := iter.data.Index() / 8
+	if !iter.primed {
+		iter.primed = true
+		if !iter.bits.Next() {
+			return iter.fail()
+		}
+		iter.cur = iter.bits.Value()
+	}
+	have := iter.bits.Index()
+	delta := want - have
+	if iter.down {
+		delta = have - want
+	}
+	if delta > 0 {
+		if !iter.bits.Skip(delta) {
+			return iter.fail()
+		}
+		iter.cur = iter.bits.Value()
+	}
+	return true
+}
+
+func (iter *nullableIterator) fail() bool {
+	iter.err = iter.bits.Err()
+	if iter.err == nil {
+		iter.err = io.ErrUnexpectedEOF
+	}
+	return false
+}
+
+func (iter *nullableIterator) Flush() error {
+	if err := iter.data.Flush(); err != nil {
+		return err
+	}
+	return iter.bits.Flush()
+}
+
+func (iter *nullableIterator) Close() error {
+	if iter.data == nil {
+		return iter.err
+	}
+	err := iter.data.Close()
+	if err2 := iter.bits.Close(); err == nil {
+		err = err2
+	}
+	if iter.err != nil {
+		err = iter.err
+	}
+	*iter = nullableIterator{err: ErrClosedIterator}
+	return err
+}
+
+var _ Iterator = (*nullableIterator)(nil)
+var _ nullSetter = (*nullableIterator)(nil)
@@ -0,0 +1,204 @@
+package bigarray
+
+import (
+	"bytes"
+	"testing"
+)
+
+func RunNullableArrayTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	opts = append(opts,
+		Nullable(),
+		BytesPerValue(8),
+		PageSize(16),
+		NumValues(20))
+
+	ba, err := New(opts...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+
+	nba, ok := ba.(NullableArray)
+	if !ok {
+		t.Errorf("New: expected NullableArray, got %T", ba)
+		return
+	}
+
+	if null, err := nba.IsNull(5); err != nil || !null {
+		t.Errorf("NullableArray.IsNull 5: expected true, got %v, %v", null, err)
+	}
+
+	iter := ba.Iterate(0, ba.Len())
+	for iter.Next() {
+		if iter.Index()%3 == 0 {
+			iter.SetValue(^uint64(0))
+		}
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("BigArray.Iterate: error: %v", err)
+	}
+
+	if err := nba.SetValueAt(4, 0); err != nil {
+		t.Errorf("NullableArray.SetValueAt 4: error: %v", err)
+	}
+	if err := nba.SetNull(9); err != nil {
+		t.Errorf("NullableArray.SetNull 9: error: %v", err)
+	}
+
+	for i := uint64(0); i < ba.Len(); i++ {
+		expect := (i%3 != 0 && i != 4) || i == 9
+		null, err := nba.IsNull(i)
+		if err != nil {
+			t.Errorf("NullableArray.IsNull %d: error: %v", i, err)
+		}
+		if null != expect {
+			t.Errorf("NullableArray.IsNull %d: expected %v, got %v", i, expect, null)
+		}
+	}
+
+	iter = ba.ReverseIterate(2, 19)
+	n := uint64(0)
+	for iter.Next() {
+		n++
+		index := iter.Index()
+		if index != 19-n {
+			t.Errorf("BigArray.ReverseIterate out of order: expected [%d], got [%d]", 19-n, index)
+		}
+		expect := (index%3 == 0 && index != 9) || index == 4
+		if iter.Valid() != expect {
+			t.Errorf("Iterator.Valid [%d]: expected %v, got %v", index, expect, iter.Valid())
+		}
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("BigArray.ReverseIterate: error: %v", err)
+	}
+
+	const expectDebug = "[18446744073709551615 . . 18446744073709551615 0 . 18446744073709551615 . . . . . 18446744073709551615 . . 18446744073709551615 . . 18446744073709551615 .]"
+	if actual := ba.Debug(); actual != expectDebug {
+		t.Errorf("BigArray.Debug: expected %s, got %s", expectDebug, actual)
+	}
+}
+
+func RunNullableViewTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	opts = append(opts, Nullable(), MaxValue(255), PageSize(16), NumValues(10))
+	a, err := New(opts...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer a.Close()
+	b, err := New(opts...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer b.Close()
+
+	a.SetValueAt(2, 20)
+	b.SetValueAt(7, 70)
+
+	rev, ok := Reversed(a).(NullableArray)
+	if !ok {
+		t.Errorf("Reversed: expected NullableArray, got %T", Reversed(a))
+		return
+	}
+	for i := uint64(0); i < rev.Len(); i++ {
+		expect := i != 7
+		if null, err := rev.IsNull(i); err != nil || null != expect {
+			t.Errorf("Reversed.IsNull %d: expected %v, got %v, %v", i, expect, null, err)
+		}
+	}
+	if err := rev.SetNull(7); err != nil {
+		t.Errorf("Reversed.SetNull 7: error: %v", err)
+	}
+	if null, _ := a.(NullableArray).IsNull(2); !null {
+		t.Errorf("Reversed.SetNull 7: expected parent [2] to be null")
+	}
+
+	cat, ok := Concat(a, b).(NullableArray)
+	if !ok {
+		t.Errorf("Concat: expected NullableArray, got %T", Concat(a, b))
+		return
+	}
+	for i := uint64(0); i < cat.Len(); i++ {
+		expect := i != 17
+		if null, err := cat.IsNull(i); err != nil || null != expect {
+			t.Errorf("Concat.IsNull %d: expected %v, got %v, %v", i, expect, null, err)
+		}
+	}
+	if err := cat.SetNull(17); err != nil {
+		t.Errorf("Concat.SetNull 17: error: %v", err)
+	}
+	if null, _ := b.(NullableArray).IsNull(7); !null {
+		t.Errorf("Concat.SetNull 17: expected part [7] to be null")
+	}
+
+	plain, err := New(MaxValue(255), NumValues(10))
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer plain.Close()
+	if _, ok := Concat(a, plain).(NullableArray); ok {
+		t.Errorf("Concat: expected a plain BigArray when a part is not nullable")
+	}
+
+	a.SetValueAt(1, 10)
+	a.SetValueAt(3, 30)
+	dst, err := New(opts...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer dst.Close()
+	if err := dst.CopyFrom(Reversed(a)); err != nil {
+		t.Errorf("CopyFrom Reversed: error: %v", err)
+	}
+	if expect, actual := "[. . . . . . 30 . 10 .]", dst.Debug(); actual != expect {
+		t.Errorf("CopyFrom Reversed: expected %s, got %s", expect, actual)
+	}
+
+	for i := uint64(0); i < plain.Len(); i++ {
+		plain.SetValueAt(i, i)
+	}
+	if err := dst.CopyFrom(plain); err != nil {
+		t.Errorf("CopyFrom plain: error: %v", err)
+	}
+	if expect, actual := plain.Debug(), dst.Debug(); actual != expect {
+		t.Errorf("CopyFrom plain: expected %s, got %s", expect, actual)
+	}
+	bits := unwrapArray(dst).(*nullableArray).bits
+	if b, _ := bits.ValueAt(1); b != 0x03 {
+		t.Errorf("CopyFrom plain: expected padding bits to be clear, got %#x", b)
+	}
+}
+
+func TestNullableViews_InMemory(t *testing.T) {
+	RunNullableViewTests(t)
+}
+
+func TestNullableViews_OnDisk(t *testing.T) {
+	RunNullableViewTests(t, OnDiskThreshold(0))
+}
+
+func TestNullableArray_InMemory(t *testing.T) {
+	RunNullableArrayTests(t)
+}
+
+func TestNullableArray_OnDisk(t *testing.T) {
+	RunNullableArrayTests(t, OnDiskThreshold(0))
+}
+
+func TestNullableArray_WithFile(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("New: expected a panic for Nullable with WithReadOnlyFile")
+		}
+	}()
+	New(Nullable(), MaxValue(255), NumValues(4), WithReadOnlyFile(bytes.NewReader(make([]byte, 4))))
+}
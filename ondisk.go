@@ -25,6 +25,10 @@ type onDiskArray struct {
 	f     File
 	p     *sync.Pool
 	cache map[uint64]*cachePage
+	st    *ioStats
+	mb    *MemoryBudget
+	dur   *durability
+	base  uint64
 	num   uint64
 	max   uint64
 	psz   uint
@@ -48,7 +52,7 @@ func (ba *onDiskArray) Len() uint64 {
 func (ba *onDiskArray) compute(index uint64) (uint64, uint64) {
 	psz := uint64(ba.psz)
 	bpv := uint64(ba.bpv)
-	offset := index * bpv
+	offset := ba.base + index*bpv
 	rounded := (offset / psz) * psz
 	offset -= rounded
 	return rounded, offset
@@ -67,9 +71,9 @@ func (ba *onDiskArray) ValueAt(index uint64) (uint64, error) {
 	} else {
 		var tmp [8]byte
 		data = tmp[0:ba.bpv]
-		offset := index * uint64(ba.bpv)
+		offset := ba.base + index*uint64(ba.bpv)
 
-		_, err := ba.f.ReadAt(data, int64(offset))
+		_, err := ba.readAt(data, int64(offset))
 		if err != nil {
 			return ^uint64(0), err
 		}
@@ -90,7 +94,7 @@ func (ba *onDiskArray) SetValueAt(index uint64, value uint64) error {
 
 	var tmp [8]byte
 	data := tmp[0:ba.bpv]
-	offset := index * uint64(ba.bpv)
+	offset := ba.base + index*uint64(ba.bpv)
 	bpvEncode(ba.bpv, data, value)
 
 	pageStart, offsetInPage := ba.compute(index)
@@ -99,7 +103,7 @@ func (ba *onDiskArray) SetValueAt(index uint64, value uint64) error {
 		copy(page.data[offsetInPage:offsetInPage+uint64(ba.bpv)], data)
 	}
 
-	_, err := ba.f.WriteAt(data, int64(offset))
+	_, err := ba.writeAt(data, int64(offset))
 	return err
 }
 
@@ -135,6 +139,9 @@ func (ba *onDiskArray) CopyFrom(src BigArray) error {
 	if src.Len() != ba.Len() {
 		panic("big arrays are not equal in size")
 	}
+	if ok, err := copyFromFast(ba, src); ok {
+		return err
+	}
 	return copyFromImpl(ba, src)
 }
 
@@ -148,7 +155,7 @@ func (ba *onDiskArray) Truncate(length uint64) error {
 	if len(ba.cache) != 0 {
 		panic("Truncate() with live iterators is undefined behavior")
 	}
-	lengthBytes := length * uint64(ba.bpv)
+	lengthBytes := ba.base + length*uint64(ba.bpv)
 	ba.num = length
 	return ba.f.Truncate(int64(lengthBytes))
 }
@@ -159,8 +166,17 @@ func (ba *onDiskArray) Freeze() error {
 }
 
 func (ba *onDiskArray) Flush() error {
+	if err := ba.flushPages(); err != nil {
+		return err
+	}
+	return ba.dur.afterFlush()
+}
+
+// flushPages writes the dirty pages, without applying the DurabilityPolicy.
+func (ba *onDiskArray) flushPages() error {
 	type flusher interface{ Flush() error }
 
+	ba.st.add(StatFlushes, 1)
 	var finalError error
 	for _, page := range ba.cache {
 		if err := flushPage(ba, page); err != nil && finalError == nil {
@@ -175,16 +191,17 @@ func (ba *onDiskArray) Flush() error {
 	return finalError
 }
 
+// Sync flushes the dirty pages and syncs the file once, regardless of the
+// DurabilityPolicy.  An error from an earlier background sync is returned
+// instead.
 func (ba *onDiskArray) Sync() error {
-	type syncer interface{ Sync() error }
-
-	if err := ba.Flush(); err != nil {
+	if err := ba.flushPages(); err != nil {
 		return err
 	}
-	if f, ok := ba.f.(syncer); ok {
-		return f.Sync()
+	if err := ba.dur.takeErr(); err != nil {
+		return err
 	}
-	return &NotImplementedError{Op: "Sync"}
+	return syncFile(ba.f)
 }
 
 func (ba *onDiskArray) Close() error {
@@ -206,20 +223,56 @@ func (ba *onDiskArray) Close() error {
 		return removeFile(ba.f)
 	}
 
+	err := ba.dur.beforeClose()
 	needClose = false
-	return ba.f.Close()
+	if err2 := ba.f.Close(); err == nil {
+		err = err2
+	}
+	return err
 }
 
 func (ba *onDiskArray) Debug() string {
 	return debugImpl(ba)
 }
 
+// Stats returns a snapshot of the array's I/O counters.
+func (ba *onDiskArray) Stats() Stats {
+	return ba.st.snapshot()
+}
+
+// readAt reads from the backing file, updating the I/O counters.
+func (ba *onDiskArray) readAt(p []byte, off int64) (int, error) {
+	n, err := ba.f.ReadAt(p, off)
+	ba.st.add(StatReadCalls, 1)
+	ba.st.add(StatBytesRead, uint64(n))
+	return n, err
+}
+
+// writeAt writes to the backing file, updating the I/O counters.
+func (ba *onDiskArray) writeAt(p []byte, off int64) (int, error) {
+	n, err := ba.f.WriteAt(p, off)
+	ba.st.add(StatWriteCalls, 1)
+	ba.st.add(StatBytesWritten, uint64(n))
+	return n, err
+}
+
+func (ba *onDiskArray) WriteTo(w io.Writer) (int64, error) {
+	return writeToDisk(w, ba)
+}
+
+func (ba *onDiskArray) MarshalBinary() ([]byte, error) {
+	return marshalImpl(ba)
+}
+
 func (ba *onDiskArray) acquirePage(off uint64) (*cachePage, error) {
+	ba.st.add(StatPageAcquires, 1)
 	page, found := ba.cache[off]
 	if found {
+		ba.st.add(StatCacheHits, 1)
 		page.refcnt++
 		return page, nil
 	}
+	ba.st.add(StatCacheMisses, 1)
 
 	var bb []byte
 	if ba.p != nil {
@@ -228,12 +281,14 @@ func (ba *onDiskArray) acquirePage(off uint64) (*cachePage, error) {
 
 	var b []byte
 	if uint(cap(bb)) >= ba.psz {
+		ba.st.add(StatPoolHits, 1)
 		b = bb[0:ba.psz]
 	} else {
+		ba.st.add(StatPoolMisses, 1)
 		b = make([]byte, ba.psz)
 	}
 
-	n, err := ba.f.ReadAt(b, int64(off))
+	n, err := ba.readAt(b, int64(off))
 	if err != nil && err != io.EOF {
 		return nil, err
 	}
@@ -247,6 +302,7 @@ func (ba *onDiskArray) acquirePage(off uint64) (*cachePage, error) {
 		dirty:  false,
 	}
 	ba.cache[off] = page
+	ba.mb.charge(uint64(ba.psz))
 	return page, nil
 }
 
@@ -262,6 +318,7 @@ func (ba *onDiskArray) disposePage(page *cachePage) {
 		return
 	}
 	delete(ba.cache, page.off)
+	ba.mb.release(uint64(ba.psz))
 	if ba.p != nil && page.buf != nil {
 		ba.p.Put(page.buf)
 	}
@@ -308,6 +365,16 @@ func (iter *onDiskIterator) Value() uint64 {
 	return iter.val
 }
 
+func (iter *onDiskIterator) Valid() bool {
+	if !iter.primed {
+		panic("must call Next() before Valid()")
+	}
+	if iter.pos >= iter.num {
+		panic("must not call Valid() after Next() returns false")
+	}
+	return true
+}
+
 func (iter *onDiskIterator) SetValue(value uint64) {
 	if !iter.primed {
 		panic("must call Next() before SetValue()")
@@ -327,11 +394,12 @@ func (iter *onDiskIterator) SetValue(value uint64) {
 
 	bpv := uint64(iter.ba.bpv)
 	index := iter.Index()
-	offset := index * bpv
+	offset := iter.ba.base + index*bpv
 	offset -= iter.page.off
 	data := iter.page.data[offset : offset+bpv]
 	bpvEncode(iter.ba.bpv, data, value)
 	iter.page.dirty = true
+	iter.val = value
 }
 
 func (iter *onDiskIterator) Skip(n uint64) bool {
@@ -358,11 +426,14 @@ func (iter *onDiskIterator) Skip(n uint64) bool {
 
 	bpv := uint64(iter.ba.bpv)
 	psz := uint64(iter.ba.psz)
-	offset := iter.Index() * bpv
+	offset := iter.ba.base + iter.Index()*bpv
 	pageOffset := (offset / psz) * psz
 
 	page := iter.page
 	if page != nil && page.off != pageOffset {
+		if page.dirty {
+			iter.ba.st.add(StatDirtyEvictions, 1)
+		}
 		err := flushPage(iter.ba, page)
 		if err != nil {
 			iter.err = err
@@ -395,6 +466,9 @@ func (iter *onDiskIterator) Flush() error {
 }
 
 func (iter *onDiskIterator) Close() error {
+	if iter.page != nil && iter.page.dirty {
+		iter.ba.st.add(StatDirtyEvictions, 1)
+	}
 	err := iter.Flush()
 	if iter.err != nil {
 		err = iter.err
@@ -438,7 +512,7 @@ func bpvEncode(bpv byte, data []byte, value uint64) {
 
 func flushPage(ba *onDiskArray, page *cachePage) error {
 	if page != nil && page.dirty {
-		_, err := ba.f.WriteAt(page.data, int64(page.off))
+		_, err := ba.writeAt(page.data, int64(page.off))
 		if err != nil {
 			return err
 		}
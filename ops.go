@@ -0,0 +1,480 @@
+package bigarray
+
+import (
+	"fmt"
+)
+
+// Fill sets every element from index (i) through index (j-1) to (value).
+func Fill(ba BigArray, i, j, value uint64) error {
+	checkRange("Fill", ba, i, j)
+	if ba.Frozen() {
+		panic("BigArray is read-only")
+	}
+
+	impl, unlock := lockImpl(ba)
+	if x, ok := impl.(*onDiskArray); ok && value <= x.max && len(x.cache) == 0 {
+		defer unlock()
+		return fillOnDisk(x, i, j, value)
+	}
+	unlock()
+
+	iter := ba.Iterate(i, j)
+	for iter.Next() {
+		iter.SetValue(value)
+	}
+	return iter.Close()
+}
+
+// Reverse reverses the order of the elements from index (i) through index
+// (j-1).  Null elements of a NullableArray stay null.
+//
+// On-disk arrays swap a page-sized block of raw bytes from the front of the
+// range with one from the back, reversing each block in memory.  Nullable
+// arrays swap blocks in the same way, read and written through Iterators.
+// Other arrays are walked by a forward Iterator and a reverse Iterator toward
+// each other, swapping as they go.
+func Reverse(ba BigArray, i, j uint64) error {
+	checkRange("Reverse", ba, i, j)
+	if ba.Frozen() {
+		panic("BigArray is read-only")
+	}
+	if j-i < 2 {
+		return nil
+	}
+
+	impl, unlock := lockImpl(ba)
+	if x, ok := impl.(*onDiskArray); ok && len(x.cache) == 0 {
+		defer unlock()
+		return reverseOnDisk(x, i, j)
+	}
+	unlock()
+	if _, ok := ba.(NullableArray); ok {
+		return reverseBlocks(ba, i, j)
+	}
+
+	fwd := ba.Iterate(i, j)
+	rev := ba.ReverseIterate(i, j)
+	for fwd.Next() && rev.Next() && fwd.Index() < rev.Index() {
+		a, aok := fwd.Value(), fwd.Valid()
+		b, bok := rev.Value(), rev.Valid()
+		setIterValue(fwd, b, bok)
+		setIterValue(rev, a, aok)
+	}
+	err := fwd.Close()
+	if err2 := rev.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// Rotate rotates the elements from index (i) through index (j-1) to the left
+// by (k) positions, so that the element at index (i+k) moves to index (i).
+//
+// The rotation is performed as three reversals, each of which is sequential.
+func Rotate(ba BigArray, i, j, k uint64) error {
+	checkRange("Rotate", ba, i, j)
+	if j > i {
+		k %= (j - i)
+	}
+	if k == 0 {
+		return nil
+	}
+	if err := Reverse(ba, i, i+k); err != nil {
+		return err
+	}
+	if err := Reverse(ba, i+k, j); err != nil {
+		return err
+	}
+	return Reverse(ba, i, j)
+}
+
+// Swap exchanges the elements at indices (a) and (b).  A null element of a
+// NullableArray is swapped as a null.
+func Swap(ba BigArray, a, b uint64) error {
+	x, err := ba.ValueAt(a)
+	if err != nil {
+		return err
+	}
+	y, err := ba.ValueAt(b)
+	if err != nil {
+		return err
+	}
+	nba, ok := ba.(NullableArray)
+	if !ok {
+		if err := ba.SetValueAt(a, y); err != nil {
+			return err
+		}
+		return ba.SetValueAt(b, x)
+	}
+
+	xnull, err := nba.IsNull(a)
+	if err != nil {
+		return err
+	}
+	ynull, err := nba.IsNull(b)
+	if err != nil {
+		return err
+	}
+	if err := setNullable(nba, a, y, ynull); err != nil {
+		return err
+	}
+	return setNullable(nba, b, x, xnull)
+}
+
+// setNullable sets the element at (index) to (value), or to null.
+func setNullable(ba NullableArray, index, value uint64, null bool) error {
+	if null {
+		return ba.SetNull(index)
+	}
+	return ba.SetValueAt(index, value)
+}
+
+// setIterValue sets the Iterator's current element to (value) if (valid) is
+// true, or to null otherwise.  Only the Iterators of nullable arrays ever
+// report an invalid element, and they all implement nullSetter.
+func setIterValue(iter Iterator, value uint64, valid bool) {
+	if valid {
+		iter.SetValue(value)
+		return
+	}
+	iter.(nullSetter).setNull()
+}
+
+// CopyRange copies (n) elements from (src), starting at index (srcOff), into
+// (dst), starting at index (dstOff).  No element copied may exceed
+// dst.MaxValue().
+//
+// (dst) and (src) may be the same array, or Slice and Reversed views of the
+// same array, in which case the ranges may overlap; the result is as if the
+// elements were first copied to a temporary buffer.  If (dst) is a
+// NullableArray, null elements of (src) are copied as nulls.
+//
+// When both arrays are on disk with the same BytesPerValue, the raw bytes are
+// moved in page-sized blocks without decoding the individual elements.
+func CopyRange(dst BigArray, dstOff uint64, src BigArray, srcOff uint64, n uint64) error {
+	checkRange("CopyRange", dst, dstOff, dstOff+n)
+	checkRange("CopyRange", src, srcOff, srcOff+n)
+	if dst.Frozen() {
+		panic("BigArray is read-only")
+	}
+	if n == 0 {
+		return nil
+	}
+
+	dp, d0, dd := viewBase(dst, dstOff)
+	sp, s0, sd := viewBase(src, srcOff)
+	backward := false
+	if dp == sp && spanOverlaps(d0, dd, s0, sd, n) {
+		if dd != sd {
+			// The ranges run in opposite directions through the same
+			// array, so neither order of copying is safe.
+			return copyRangeStaged(dst, dstOff, src, srcOff, n)
+		}
+		if d0 == s0 {
+			return nil
+		}
+		backward = (d0 > s0) == (dd > 0)
+	}
+
+	if ok, err := copyRangeFast(dst, dstOff, src, srcOff, n, backward); ok {
+		return err
+	}
+
+	var srcIter, dstIter Iterator
+	if backward {
+		srcIter = src.ReverseIterate(srcOff, srcOff+n)
+		dstIter = dst.ReverseIterate(dstOff, dstOff+n)
+	} else {
+		srcIter = src.Iterate(srcOff, srcOff+n)
+		dstIter = dst.Iterate(dstOff, dstOff+n)
+	}
+	_, nullable := dst.(NullableArray)
+	for srcIter.Next() && dstIter.Next() {
+		if nullable && !srcIter.Valid() {
+			dstIter.(nullSetter).setNull()
+		} else {
+			dstIter.SetValue(srcIter.Value())
+		}
+	}
+	err := dstIter.Close()
+	if err2 := srcIter.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// viewBase looks through Slice and Reversed views to find the array which
+// holds the elements of (ba).  It returns that array, the index within it of
+// element (off) of (ba), and +1 or -1 for the direction in which the
+// following elements of (ba) lie.
+func viewBase(ba BigArray, off uint64) (BigArray, uint64, int) {
+	dir := 1
+	for {
+		switch x := ba.(type) {
+		case *sliceArray:
+			ba, off = x.ba, x.base+off
+		case *nullableSliceArray:
+			ba, off = x.ba, x.base+off
+		case *reversedArray:
+			ba, off, dir = x.ba, x.end-1-off, -dir
+		case *nullableReversedArray:
+			ba, off, dir = x.ba, x.end-1-off, -dir
+		case *dynamicArray:
+			// The handle identifies the array as well as its
+			// representation does, and never changes.
+			return x, off, dir
+		default:
+			return unwrapArray(ba), off, dir
+		}
+	}
+}
+
+// spanOverlaps returns true if two runs of (n) elements, starting at (a) and
+// (b) and running in the given directions, share any element.
+func spanOverlaps(a uint64, adir int, b uint64, bdir int, n uint64) bool {
+	alo, blo := a, b
+	if adir < 0 {
+		alo = a - (n - 1)
+	}
+	if bdir < 0 {
+		blo = b - (n - 1)
+	}
+	return alo < blo+n && blo < alo+n
+}
+
+// copyRangeStaged copies the elements through a temporary array.
+func copyRangeStaged(dst BigArray, dstOff uint64, src BigArray, srcOff uint64, n uint64) error {
+	opts := []Option{MaxValue(src.MaxValue()), NumValues(n)}
+	if _, ok := src.(NullableArray); ok {
+		opts = append(opts, Nullable())
+	}
+	tmp, err := New(opts...)
+	if err != nil {
+		return err
+	}
+	err = CopyRange(tmp, 0, src, srcOff, n)
+	if err == nil {
+		err = CopyRange(dst, dstOff, tmp, 0, n)
+	}
+	if err2 := tmp.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func checkRange(op string, ba BigArray, i, j uint64) {
+	if i > j {
+		panic(fmt.Errorf("%s: i > j: i=%d j=%d", op, i, j))
+	}
+	if j > ba.Len() {
+		panic(fmt.Errorf("%s: j out of range: j=%d len=%d", op, j, ba.Len()))
+	}
+}
+
+// copyRangeFast handles the cases where both arrays share a representation
+// that can be copied without decoding.  It returns false if the generic
+// Iterator-based copy must be used instead.
+func copyRangeFast(dst BigArray, dstOff uint64, src BigArray, srcOff uint64, n uint64, backward bool) (bool, error) {
+	d, unlockDst := lockImpl(dst)
+	defer unlockDst()
+	s := d
+	if src != dst {
+		var unlockSrc func()
+		s, unlockSrc = lockImpl(src)
+		defer unlockSrc()
+	}
+	switch x := d.(type) {
+	case *inMemoryArray8:
+		if y, ok := s.(*inMemoryArray8); ok && y.max <= x.max {
+			copy(x.data[dstOff:dstOff+n], y.data[srcOff:srcOff+n])
+			return true, nil
+		}
+	case *inMemoryArray16:
+		if y, ok := s.(*inMemoryArray16); ok && y.max <= x.max {
+			copy(x.data[dstOff:dstOff+n], y.data[srcOff:srcOff+n])
+			return true, nil
+		}
+	case *inMemoryArray32:
+		if y, ok := s.(*inMemoryArray32); ok && y.max <= x.max {
+			copy(x.data[dstOff:dstOff+n], y.data[srcOff:srcOff+n])
+			return true, nil
+		}
+	case *inMemoryArray64:
+		if y, ok := s.(*inMemoryArray64); ok && y.max <= x.max {
+			copy(x.data[dstOff:dstOff+n], y.data[srcOff:srcOff+n])
+			return true, nil
+		}
+	case *onDiskArray:
+		if y, ok := s.(*onDiskArray); ok && y.bpv == x.bpv && y.max <= x.max && len(x.cache) == 0 && len(y.cache) == 0 {
+			return true, copyBytesOnDisk(x, dstOff, y, srcOff, n, backward)
+		}
+	}
+	return false, nil
+}
+
+// copyBytesOnDisk moves the raw encoded bytes of (n) elements between two
+// on-disk arrays with the same BytesPerValue, one page-sized block at a time.
+// Neither array may have any live pages.
+func copyBytesOnDisk(dst *onDiskArray, dstOff uint64, src *onDiskArray, srcOff uint64, n uint64, backward bool) error {
+	if err := src.Flush(); err != nil {
+		return err
+	}
+
+	bpv := uint64(dst.bpv)
+	total := n * bpv
+	from := src.base + srcOff*bpv
+	to := dst.base + dstOff*bpv
+	buf := make([]byte, dst.psz)
+	block := uint64(len(buf))
+
+	for done := uint64(0); done < total; {
+		size := total - done
+		if size > block {
+			size = block
+		}
+		at := done
+		if backward {
+			at = total - done - size
+		}
+		if _, err := src.readAt(buf[0:size], int64(from+at)); err != nil {
+			return err
+		}
+		if _, err := dst.writeAt(buf[0:size], int64(to+at)); err != nil {
+			return err
+		}
+		done += size
+	}
+	return nil
+}
+
+// reverseOnDisk reverses the range by swapping a page-sized block of raw bytes
+// from the front with a block of the same size from the back, each reversed in
+// memory, until the blocks meet.  The array may not have any live pages.
+func reverseOnDisk(ba *onDiskArray, i, j uint64) error {
+	bpv := uint64(ba.bpv)
+	step := uint64(ba.psz) / bpv
+	front := make([]byte, step*bpv)
+	back := make([]byte, step*bpv)
+	for lo, hi := i, j; hi-lo >= 2; {
+		n := (hi - lo) / 2
+		if n > step {
+			n = step
+		}
+		p := front[0 : n*bpv]
+		q := back[0 : n*bpv]
+		at := int64(ba.base + lo*bpv)
+		bt := int64(ba.base + (hi-n)*bpv)
+		if _, err := ba.readAt(p, at); err != nil {
+			return err
+		}
+		if _, err := ba.readAt(q, bt); err != nil {
+			return err
+		}
+		reverseRaw(p, bpv)
+		reverseRaw(q, bpv)
+		if _, err := ba.writeAt(q, at); err != nil {
+			return err
+		}
+		if _, err := ba.writeAt(p, bt); err != nil {
+			return err
+		}
+		lo += n
+		hi -= n
+	}
+	return nil
+}
+
+// reverseBlocks reverses the range like reverseOnDisk, but reads and writes
+// each block through its own Iterator, closing it before the next is created.
+// It is used for nullable arrays, whose Iterators each cache a byte of the
+// bitset, so a forward and a reverse Iterator which meet in the same byte
+// would overwrite each other's nulls.
+func reverseBlocks(ba BigArray, i, j uint64) error {
+	step := chunkSize((j - i) / 2)
+	front := make([]uint64, step)
+	back := make([]uint64, step)
+	fvalid := make([]bool, step)
+	bvalid := make([]bool, step)
+	for lo, hi := i, j; hi-lo >= 2; {
+		n := (hi - lo) / 2
+		if n > step {
+			n = step
+		}
+		if err := readBlock(ba, lo, front[0:n], fvalid[0:n]); err != nil {
+			return err
+		}
+		if err := readBlock(ba, hi-n, back[0:n], bvalid[0:n]); err != nil {
+			return err
+		}
+		if err := writeBlockReversed(ba, lo, back[0:n], bvalid[0:n]); err != nil {
+			return err
+		}
+		if err := writeBlockReversed(ba, hi-n, front[0:n], fvalid[0:n]); err != nil {
+			return err
+		}
+		lo += n
+		hi -= n
+	}
+	return nil
+}
+
+// readBlock reads len(values) elements, and whether each is valid, starting
+// at index (at).
+func readBlock(ba BigArray, at uint64, values []uint64, valid []bool) error {
+	iter := ba.Iterate(at, at+uint64(len(values)))
+	for k := 0; iter.Next(); k++ {
+		values[k] = iter.Value()
+		valid[k] = iter.Valid()
+	}
+	return iter.Close()
+}
+
+// writeBlockReversed writes the elements read by readBlock, in reverse order,
+// starting at index (at).
+func writeBlockReversed(ba BigArray, at uint64, values []uint64, valid []bool) error {
+	iter := ba.Iterate(at, at+uint64(len(values)))
+	for k := len(values); iter.Next(); {
+		k--
+		setIterValue(iter, values[k], valid[k])
+	}
+	return iter.Close()
+}
+
+// reverseRaw reverses the order of the encoded values of (bpv) bytes each in
+// (raw).
+func reverseRaw(raw []byte, bpv uint64) {
+	if len(raw) == 0 {
+		return
+	}
+	for a, b := uint64(0), uint64(len(raw))-bpv; a < b; a, b = a+bpv, b-bpv {
+		for k := uint64(0); k < bpv; k++ {
+			raw[a+k], raw[b+k] = raw[b+k], raw[a+k]
+		}
+	}
+}
+
+// fillOnDisk writes a page-sized block of encoded copies of (value) over the
+// range, without reading the existing contents.  The array may not have any
+// live pages.
+func fillOnDisk(ba *onDiskArray, i, j, value uint64) error {
+	bpv := uint64(ba.bpv)
+	block := uint64(ba.psz)
+	buf := make([]byte, block)
+	for off := uint64(0); off < block; off += bpv {
+		bpvEncode(ba.bpv, buf[off:off+bpv], value)
+	}
+
+	total := (j - i) * bpv
+	to := ba.base + i*bpv
+	for done := uint64(0); done < total; {
+		size := total - done
+		if size > block {
+			size = block
+		}
+		if _, err := ba.writeAt(buf[0:size], int64(to+done)); err != nil {
+			return err
+		}
+		done += size
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package bigarray
+
+import (
+	"testing"
+)
+
+func RunOpsTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	ba, err := New(append(opts, MaxValue(100), PageSize(4), NumValues(12))...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+
+	check := func(op string, expect string) {
+		t.Helper()
+		if actual := ba.Debug(); actual != expect {
+			t.Errorf("%s: expected %s, got %s", op, expect, actual)
+		}
+	}
+
+	if err := Fill(ba, 2, 9, 7); err != nil {
+		t.Errorf("Fill: error: %v", err)
+	}
+	check("Fill", "[0 0 7 7 7 7 7 7 7 0 0 0]")
+
+	for i := uint64(0); i < ba.Len(); i++ {
+		ba.SetValueAt(i, i)
+	}
+
+	if err := Reverse(ba, 1, 10); err != nil {
+		t.Errorf("Reverse: error: %v", err)
+	}
+	check("Reverse", "[0 9 8 7 6 5 4 3 2 1 10 11]")
+
+	if err := Rotate(ba, 1, 10, 3); err != nil {
+		t.Errorf("Rotate: error: %v", err)
+	}
+	check("Rotate", "[0 6 5 4 3 2 1 9 8 7 10 11]")
+
+	if err := Swap(ba, 0, 11); err != nil {
+		t.Errorf("Swap: error: %v", err)
+	}
+	check("Swap", "[11 6 5 4 3 2 1 9 8 7 10 0]")
+
+	if err := CopyRange(ba, 3, ba, 1, 6); err != nil {
+		t.Errorf("CopyRange forward overlap: error: %v", err)
+	}
+	check("CopyRange forward overlap", "[11 6 5 6 5 4 3 2 1 7 10 0]")
+
+	if err := CopyRange(ba, 0, ba, 5, 7); err != nil {
+		t.Errorf("CopyRange backward overlap: error: %v", err)
+	}
+	check("CopyRange backward overlap", "[4 3 2 1 7 10 0 2 1 7 10 0]")
+
+	for i := uint64(0); i < ba.Len(); i++ {
+		ba.SetValueAt(i, i)
+	}
+	if err := CopyRange(Slice(ba, 2, 12), 0, Slice(ba, 0, 10), 0, 6); err != nil {
+		t.Errorf("CopyRange overlapping slices: error: %v", err)
+	}
+	check("CopyRange overlapping slices", "[0 1 0 1 2 3 4 5 8 9 10 11]")
+
+	if err := CopyRange(Reversed(ba), 2, Reversed(ba), 0, 5); err != nil {
+		t.Errorf("CopyRange overlapping reversed: error: %v", err)
+	}
+	check("CopyRange overlapping reversed", "[0 1 0 1 2 5 8 9 10 11 10 11]")
+
+	if err := CopyRange(ba, 0, Reversed(ba), 6, 6); err != nil {
+		t.Errorf("CopyRange opposite directions: error: %v", err)
+	}
+	check("CopyRange opposite directions", "[5 2 1 0 1 0 8 9 10 11 10 11]")
+
+	other, err := New(MaxValue(1000), PageSize(8), NumValues(5))
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer other.Close()
+	Fill(other, 0, 5, 50)
+	if err := CopyRange(ba, 8, other, 1, 3); err != nil {
+		t.Errorf("CopyRange between arrays: error: %v", err)
+	}
+	check("CopyRange between arrays", "[5 2 1 0 1 0 8 9 50 50 50 11]")
+}
+
+func RunNullableOpsTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	ba, err := New(append(opts, Nullable(), MaxValue(100), PageSize(4), NumValues(12))...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+	for i := uint64(0); i < ba.Len(); i++ {
+		if i%3 != 0 {
+			ba.SetValueAt(i, i)
+		}
+	}
+
+	check := func(op string, expect string) {
+		t.Helper()
+		if actual := ba.Debug(); actual != expect {
+			t.Errorf("%s: expected %s, got %s", op, expect, actual)
+		}
+	}
+
+	if err := CopyRange(ba, 1, ba, 0, 5); err != nil {
+		t.Errorf("CopyRange: error: %v", err)
+	}
+	check("CopyRange", "[. . 1 2 . 4 . 7 8 . 10 11]")
+
+	if err := DeleteRange(ba, 5, 8); err != nil {
+		t.Errorf("DeleteRange: error: %v", err)
+	}
+	check("DeleteRange", "[. . 1 2 . 8 . 10 11]")
+
+	if err := CopyRange(Reversed(ba), 0, ba, 0, 4); err != nil {
+		t.Errorf("CopyRange through Reversed: error: %v", err)
+	}
+	check("CopyRange through Reversed", "[. . 1 2 . 2 1 . .]")
+
+	if err := Reverse(ba, 1, 6); err != nil {
+		t.Errorf("Reverse: error: %v", err)
+	}
+	check("Reverse", "[. 2 . 2 1 . 1 . .]")
+
+	if err := Rotate(ba, 0, 9, 2); err != nil {
+		t.Errorf("Rotate: error: %v", err)
+	}
+	check("Rotate", "[. 2 1 . 1 . . . 2]")
+
+	if err := Swap(ba, 0, 1); err != nil {
+		t.Errorf("Swap: error: %v", err)
+	}
+	check("Swap", "[2 . 1 . 1 . . . 2]")
+}
+
+func TestReverse_Blocks(t *testing.T) {
+	const num = 14
+	ba, err := New(BytesPerValue(2), PageSize(8), NumValues(num), OnDiskThreshold(0))
+	if err != nil {
+		t.Fatalf("New: error: %v", err)
+	}
+	defer ba.Close()
+
+	expect := make([]uint64, num)
+	for i := uint64(0); i < num; i++ {
+		for j := i; j <= num; j++ {
+			for k := uint64(0); k < num; k++ {
+				ba.SetValueAt(k, k*1000)
+				expect[k] = k * 1000
+			}
+			for a, b := i, j; a+1 < b; a, b = a+1, b-1 {
+				expect[a], expect[b-1] = expect[b-1], expect[a]
+			}
+			if err := Reverse(ba, i, j); err != nil {
+				t.Fatalf("Reverse %d:%d: error: %v", i, j, err)
+			}
+			for k := uint64(0); k < num; k++ {
+				if value, _ := ba.ValueAt(k); value != expect[k] {
+					t.Errorf("Reverse %d:%d: expected [%d]=%d, got %d", i, j, k, expect[k], value)
+				}
+			}
+		}
+	}
+}
+
+func TestOps_InMemory(t *testing.T) {
+	RunOpsTests(t)
+}
+
+func TestOps_OnDisk(t *testing.T) {
+	RunOpsTests(t, OnDiskThreshold(0))
+}
+
+func TestNullableOps_InMemory(t *testing.T) {
+	RunNullableOpsTests(t)
+}
+
+func TestNullableOps_OnDisk(t *testing.T) {
+	RunNullableOpsTests(t, OnDiskThreshold(0))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"sync"
+	"time"
 )
 
 const (
@@ -18,10 +19,21 @@ type options struct {
 	diskThreshold      uint64
 	backingFile        File
 	bufferPool         *sync.Pool
+	observer           Observer
+	budget             *MemoryBudget
+	tempDir            string
+	tempPrefix         string
+	syncInterval       time.Duration
 	pageSize           uint
+	recordSize         uint
 	bytesPerValue      byte
 	diskThresholdIsSet bool
 	isReadOnly         bool
+	isNullable         bool
+	autoWiden          bool
+	migratable         bool
+	autoMigrate        bool
+	durability         DurabilityPolicy
 }
 
 func (o *options) apply(opts ...Option) {
@@ -45,6 +57,21 @@ func (o *options) populate() {
 		}
 	}
 
+	if o.autoWiden {
+		o.maxValue = calcBPVToMax(o.bytesPerValue)
+	}
+
+	o.populatePaging(uint(o.bytesPerValue), "value")
+}
+
+func (o *options) populateRecords() {
+	if o.recordSize == 0 {
+		panic(errors.New("must specify RecordSize"))
+	}
+	o.populatePaging(o.recordSize, "record")
+}
+
+func (o *options) populatePaging(unit uint, what string) {
 	if !o.diskThresholdIsSet {
 		o.diskThreshold = defaultOnDiskThreshold
 	}
@@ -52,26 +79,29 @@ func (o *options) populate() {
 	if o.pageSize == 0 {
 		o.pageSize = defaultPageSize
 	}
-	if o.pageSize < uint(o.bytesPerValue) {
-		panic(errors.New("PageSize must be at least as large as a single value"))
+	if o.pageSize < unit {
+		panic(fmt.Errorf("PageSize must be at least as large as a single %s", what))
 	}
-	o.pageSize = (o.pageSize / uint(o.bytesPerValue)) * uint(o.bytesPerValue)
+	o.pageSize = (o.pageSize / unit) * unit
 }
 
 func (o options) debugString() string {
 	hasFile := (o.backingFile != nil)
 	hasPool := (o.bufferPool != nil)
 	return fmt.Sprintf(
-		"{num:%d max:%d bpv:%d odt:%d odtset:%v psz:%d file:%v pool:%v ro:%v}",
+		"{num:%d max:%d bpv:%d odt:%d odtset:%v psz:%d rsz:%d file:%v pool:%v ro:%v null:%v widen:%v}",
 		o.numValues,
 		o.maxValue,
 		o.bytesPerValue,
 		o.diskThreshold,
 		o.diskThresholdIsSet,
 		o.pageSize,
+		o.recordSize,
 		hasFile,
 		hasPool,
-		o.isReadOnly)
+		o.isReadOnly,
+		o.isNullable,
+		o.autoWiden)
 }
 
 // Option is a behavior customization for New.
@@ -106,6 +136,14 @@ func BytesPerValue(bpv uint8) Option {
 	return func(o *options) { o.bytesPerValue = bpv }
 }
 
+// RecordSize specifies the number of bytes in each record of a RecordArray.
+//
+// RecordSize must be specified for all record arrays, and is ignored by New.
+//
+func RecordSize(size uint) Option {
+	return func(o *options) { o.recordSize = size }
+}
+
 // OnDiskThreshold specifies the maximum memory usage (bytes) for an in-memory
 // BigArray.  Arrays larger than this will be backed automatically by a
 // temporary file.  The default is 256 MiB.
@@ -134,6 +172,102 @@ func WithPool(pool *sync.Pool) Option {
 	return func(o *options) { o.bufferPool = pool }
 }
 
+// WithObserver specifies an Observer which will be notified of every change
+// to the I/O counters of an on-disk array.  See StatsOf.
+func WithObserver(obs Observer) Option {
+	return func(o *options) { o.observer = obs }
+}
+
+// WithMemoryBudget specifies a MemoryBudget which the array's memory use is
+// charged against.  If the budget is exhausted, the array is created on disk
+// even if it is smaller than the OnDiskThreshold, and an in-memory array may
+// later be moved to disk to make room for other arrays.  See MemoryBudget.
+//
+func WithMemoryBudget(mb *MemoryBudget) Option {
+	return func(o *options) { o.budget = mb }
+}
+
+// TempDir specifies the directory in which temporary files are created for
+// on-disk arrays.  The default, or "", is os.TempDir().
+//
+func TempDir(dir string) Option {
+	return func(o *options) { o.tempDir = dir }
+}
+
+// TempPrefix specifies the prefix of the names of temporary files created for
+// on-disk arrays.  The default, or "", is "bigarray".  See CleanupOrphans.
+//
+func TempPrefix(prefix string) Option {
+	return func(o *options) { o.tempPrefix = prefix }
+}
+
+// Durability specifies when an on-disk array backed by WithFile asks the OS
+// to commit its writes to stable storage.  The default is SyncNever.
+//
+// Syncing uses fdatasync where available, and otherwise the file's Sync
+// method.  Arrays in temporary files are never synced, since the files do
+// not outlive the process.
+//
+func Durability(policy DurabilityPolicy) Option {
+	return func(o *options) { o.durability = policy }
+}
+
+// SyncInterval specifies how often the SyncPeriodically policy syncs the
+// file.  The default, or 0, is one second.
+//
+func SyncInterval(interval time.Duration) Option {
+	return func(o *options) { o.syncInterval = interval }
+}
+
+// Nullable specifies that each element of the array may be null.  The array
+// returned by New will implement NullableArray.
+//
+// The validity of each element is tracked in a separate bitset, which is kept
+// in memory or on disk according to the same rules as the values themselves.
+// Nullable cannot be combined with WithFile or WithReadOnlyFile: the file has
+// nowhere to store the bitset, so its existing values would all read as null.
+//
+func Nullable() Option {
+	return func(o *options) { o.isNullable = true }
+}
+
+// AutoWiden specifies that the array should grow to a wider BytesPerValue,
+// instead of panicking, when a value larger than MaxValue() is written.
+//
+// In-memory arrays are re-encoded into a new allocation, while on-disk arrays
+// are rewritten in place within their backing file.  Outstanding iterators
+// are migrated to the new encoding and continue from the same position.
+//
+// With AutoWiden, MaxValue only selects the initial BytesPerValue; the array's
+// MaxValue() is always the largest value representable at its current width.
+//
+func AutoWiden() Option {
+	return func(o *options) { o.autoWiden = true }
+}
+
+// Migratable specifies that the array should implement MigratableArray, so
+// that it can be moved between memory and disk after it has been created.
+//
+func Migratable() Option {
+	return func(o *options) { o.migratable = true }
+}
+
+// AutoMigrate implies Migratable, and specifies that the array should also
+// be moved automatically whenever Truncate, DropPrefix, DeleteRange, or
+// InsertAt changes its size across the OnDiskThreshold: to memory when it
+// shrinks below the threshold, and to disk when it grows to meet it.
+//
+// Only arrays in temporary files are moved to memory; an array backed by
+// WithFile stays in its file.  With a MemoryBudget, an array is only moved to
+// memory if the budget can accommodate it.
+//
+func AutoMigrate() Option {
+	return func(o *options) {
+		o.migratable = true
+		o.autoMigrate = true
+	}
+}
+
 // WithFile specifies the read-write file handle which will back the array.
 func WithFile(file File) Option {
 	return func(p *options) { p.backingFile = file }
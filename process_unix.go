@@ -0,0 +1,14 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package bigarray
+
+import (
+	"syscall"
+)
+
+// processExists probes (pid) with signal 0, which fails with ESRCH only if
+// there is no such process.
+func processExists(pid int) bool {
+	return syscall.Kill(pid, 0) != syscall.ESRCH
+}
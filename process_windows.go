@@ -0,0 +1,16 @@
+package bigarray
+
+import (
+	"os"
+)
+
+// processExists opens a handle to (pid), which fails if there is no such
+// process.
+func processExists(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
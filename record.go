@@ -0,0 +1,336 @@
+package bigarray
+
+import (
+	"fmt"
+	"io"
+)
+
+// RecordArray provides an interface for dealing with very large arrays of
+// fixed-width records that don't necessarily fit in memory.
+//
+// Each record is an opaque run of RecordSize() bytes.  Callers are
+// responsible for encoding and decoding the fields within a record.
+type RecordArray interface {
+	// Frozen returns true if this array is read-only.
+	Frozen() bool
+
+	// RecordSize returns the number of bytes in each record.
+	RecordSize() uint
+
+	// Len returns the number of records in this array.
+	Len() uint64
+
+	// RecordAt copies the record at the given index into dst, which must
+	// be at least RecordSize() bytes long.
+	//
+	// On-disk arrays have very slow random access.  If your accesses are
+	// sequential or roughly sequential, you should consider using a
+	// RecordIterator.
+	RecordAt(uint64, []byte) error
+
+	// SetRecordAt replaces the record at the given index.  The provided
+	// record must be exactly RecordSize() bytes long.
+	//
+	// On-disk arrays have very slow random access.  If your accesses are
+	// sequential or roughly sequential, you should consider using a
+	// RecordIterator.
+	SetRecordAt(uint64, []byte) error
+
+	// Iterate returns a RecordIterator that starts at index (i) and stops
+	// at index (j-1).
+	Iterate(i, j uint64) RecordIterator
+
+	// ReverseIterate returns a RecordIterator that starts at index (j-1)
+	// and stops at index (i).
+	ReverseIterate(i, j uint64) RecordIterator
+
+	// Truncate trims the array to the given length.
+	Truncate(uint64) error
+
+	// Freeze makes the array read-only.
+	Freeze() error
+
+	// Flush ensures that all pending writes have reached the OS.
+	Flush() error
+
+	// Sync flushes pending writes and then asks the OS to commit them to
+	// stable storage.  Sync is a no-op for in-memory arrays.
+	Sync() error
+
+	// Close flushes any writes and frees the resources used by the array.
+	Close() error
+}
+
+// RecordIterator provides an interface for fast sequential access to a
+// RecordArray.  It follows the same usage pattern as Iterator.
+type RecordIterator interface {
+	// Next advances the iterator to the next index and returns true, or
+	// returns false if the end of the iteration has been reached or if an
+	// error has occurred.
+	Next() bool
+
+	// Skip(n) is equivalent to calling Next() n times, but faster.
+	Skip(uint64) bool
+
+	// Index returns the index of the current record.
+	Index() uint64
+
+	// Record returns the bytes of the current record.
+	//
+	// The returned slice aliases the iterator's internal buffer: it is
+	// only valid until the next call to Next(), Skip(), or Close(), and
+	// it must not be modified.  Use SetRecord to change the record.
+	Record() []byte
+
+	// SetRecord replaces the bytes of the current record.
+	SetRecord([]byte)
+
+	// Err returns the error which caused Next() to return false.
+	Err() error
+
+	// Flush ensures that all pending writes have reached the OS.
+	Flush() error
+
+	// Close flushes writes and frees the resources used by the iterator.
+	Close() error
+}
+
+// NewRecordArray constructs a RecordArray instance.
+//
+// RecordSize and NumValues must be specified.  MaxValue and BytesPerValue are
+// ignored.
+//
+func NewRecordArray(opts ...Option) (RecordArray, error) {
+	var o options
+	o.apply(opts...)
+	o.populateRecords()
+
+	numBytes := o.numValues * uint64(o.recordSize)
+	if o.backingFile == nil && numBytes < o.diskThreshold {
+		ra := &inMemoryRecordArray{
+			data: make([]byte, numBytes),
+			rsz:  o.recordSize,
+			ro:   o.isReadOnly,
+		}
+		return ra, nil
+	}
+
+	doc := false
+	if o.backingFile == nil {
+		var err error
+		o.backingFile, err = createTempFile(o, numBytes)
+		if err != nil {
+			return nil, err
+		}
+		doc = true
+	}
+
+	ra := &onDiskRecordArray{
+		ba: &onDiskArray{
+			f:     o.backingFile,
+			p:     o.bufferPool,
+			cache: make(map[uint64]*cachePage),
+			st:    newIOStats(o.observer),
+			mb:    o.budget,
+			dur:   newDurability(o, doc),
+			num:   numBytes,
+			max:   calcBPVToMax(1),
+			psz:   o.pageSize,
+			bpv:   1,
+			ro:    o.isReadOnly,
+			doc:   doc,
+		},
+		rsz: o.recordSize,
+	}
+	return ra, nil
+}
+
+type inMemoryRecordArray struct {
+	data []byte
+	rsz  uint
+	ro   bool
+}
+
+func (ra *inMemoryRecordArray) Frozen() bool {
+	return ra.ro
+}
+
+func (ra *inMemoryRecordArray) RecordSize() uint {
+	return ra.rsz
+}
+
+func (ra *inMemoryRecordArray) Len() uint64 {
+	return uint64(len(ra.data)) / uint64(ra.rsz)
+}
+
+func (ra *inMemoryRecordArray) record(index uint64) []byte {
+	rsz := uint64(ra.rsz)
+	return ra.data[index*rsz : (index+1)*rsz]
+}
+
+func (ra *inMemoryRecordArray) RecordAt(index uint64, dst []byte) error {
+	if index >= ra.Len() {
+		return io.EOF
+	}
+	copy(dst[0:ra.rsz], ra.record(index))
+	return nil
+}
+
+func (ra *inMemoryRecordArray) SetRecordAt(index uint64, rec []byte) error {
+	if ra.ro {
+		panic("RecordArray is read-only")
+	}
+	if uint(len(rec)) != ra.rsz {
+		panic(fmt.Sprintf("record has wrong size: size %d vs expected %d", len(rec), ra.rsz))
+	}
+	if index >= ra.Len() {
+		return io.EOF
+	}
+	copy(ra.record(index), rec)
+	return nil
+}
+
+func (ra *inMemoryRecordArray) Iterate(i, j uint64) RecordIterator {
+	if i > j {
+		panic(fmt.Errorf("inMemoryRecordArray.Iterate: i > j: i=%d j=%d", i, j))
+	}
+	return &inMemoryRecordIterator{
+		ra:   ra,
+		base: i,
+		num:  (j - i),
+	}
+}
+
+func (ra *inMemoryRecordArray) ReverseIterate(i, j uint64) RecordIterator {
+	if i > j {
+		panic(fmt.Errorf("inMemoryRecordArray.ReverseIterate: i > j: i=%d j=%d", i, j))
+	}
+	return &inMemoryRecordIterator{
+		ra:   ra,
+		base: i,
+		num:  (j - i),
+		down: true,
+	}
+}
+
+func (ra *inMemoryRecordArray) Truncate(n uint64) error {
+	if ra.ro {
+		panic("RecordArray is read-only")
+	}
+	if n > ra.Len() {
+		panic("cannot grow a record array")
+	}
+	ra.data = ra.data[0 : n*uint64(ra.rsz)]
+	return nil
+}
+
+func (ra *inMemoryRecordArray) Freeze() error {
+	ra.ro = true
+	return nil
+}
+
+func (ra *inMemoryRecordArray) Flush() error {
+	return nil
+}
+
+func (ra *inMemoryRecordArray) Sync() error {
+	return nil
+}
+
+func (ra *inMemoryRecordArray) Close() error {
+	return nil
+}
+
+var _ RecordArray = (*inMemoryRecordArray)(nil)
+
+type inMemoryRecordIterator struct {
+	ra     *inMemoryRecordArray
+	rec    []byte
+	err    error
+	base   uint64
+	pos    uint64
+	num    uint64
+	primed bool
+	down   bool
+}
+
+func (iter *inMemoryRecordIterator) Err() error { return iter.err }
+func (iter *inMemoryRecordIterator) Next() bool { return iter.Skip(1) }
+
+func (iter *inMemoryRecordIterator) Index() uint64 {
+	if !iter.primed {
+		panic(fmt.Errorf("must call Next() before Index()"))
+	}
+	if iter.pos >= iter.num {
+		panic(fmt.Errorf("must not call Index() after Next() returns false"))
+	}
+	if iter.down {
+		return iter.base + (iter.num - iter.pos - 1)
+	}
+	return iter.base + iter.pos
+}
+
+func (iter *inMemoryRecordIterator) Record() []byte {
+	if !iter.primed {
+		panic(fmt.Errorf("must call Next() before Record()"))
+	}
+	if iter.pos >= iter.num {
+		panic(fmt.Errorf("must not call Record() after Next() returns false"))
+	}
+	return iter.rec
+}
+
+func (iter *inMemoryRecordIterator) SetRecord(rec []byte) {
+	if !iter.primed {
+		panic(fmt.Errorf("must call Next() before SetRecord()"))
+	}
+	if iter.pos >= iter.num {
+		panic(fmt.Errorf("must not call SetRecord() after Next() returns false"))
+	}
+	if iter.err != nil {
+		return
+	}
+	iter.err = iter.ra.SetRecordAt(iter.Index(), rec)
+}
+
+func (iter *inMemoryRecordIterator) Skip(n uint64) bool {
+	if iter.pos > iter.num {
+		panic(fmt.Sprintf("iter.pos=%d iter.num=%d", iter.pos, iter.num))
+	}
+	if n == 0 && !iter.primed {
+		panic(fmt.Errorf("must call Next() before Skip(0)"))
+	}
+	if iter.err != nil {
+		return false
+	}
+	if !iter.primed {
+		n--
+		iter.primed = true
+	}
+	if n >= (iter.num - iter.pos) {
+		iter.pos = iter.num
+		iter.rec = nil
+		return false
+	}
+	iter.pos += n
+	index := iter.Index()
+	if index >= iter.ra.Len() {
+		iter.err = io.EOF
+		iter.rec = nil
+		return false
+	}
+	iter.rec = iter.ra.record(index)
+	return true
+}
+
+func (iter *inMemoryRecordIterator) Flush() error {
+	return nil
+}
+
+func (iter *inMemoryRecordIterator) Close() error {
+	err := iter.err
+	*iter = inMemoryRecordIterator{err: ErrClosedIterator}
+	return err
+}
+
+var _ RecordIterator = (*inMemoryRecordIterator)(nil)
@@ -0,0 +1,256 @@
+package bigarray
+
+import (
+	"fmt"
+	"io"
+)
+
+// onDiskRecordArray reuses the paging machinery of onDiskArray.  The
+// underlying onDiskArray is a byte array (bpv=1) whose page size is a whole
+// multiple of the record size, so that no record ever straddles two pages.
+type onDiskRecordArray struct {
+	ba  *onDiskArray
+	rsz uint
+}
+
+func (ra *onDiskRecordArray) Frozen() bool {
+	return ra.ba.ro
+}
+
+func (ra *onDiskRecordArray) RecordSize() uint {
+	return ra.rsz
+}
+
+func (ra *onDiskRecordArray) Len() uint64 {
+	return ra.ba.num / uint64(ra.rsz)
+}
+
+func (ra *onDiskRecordArray) RecordAt(index uint64, dst []byte) error {
+	if index >= ra.Len() {
+		return io.EOF
+	}
+
+	rsz := uint64(ra.rsz)
+	offset := index * rsz
+	pageStart, offsetInPage := ra.ba.compute(offset)
+	page, found := ra.ba.cache[pageStart]
+	if found {
+		copy(dst[0:rsz], page.data[offsetInPage:offsetInPage+rsz])
+		return nil
+	}
+
+	_, err := ra.ba.readAt(dst[0:rsz], int64(ra.ba.base+offset))
+	return err
+}
+
+func (ra *onDiskRecordArray) SetRecordAt(index uint64, rec []byte) error {
+	if ra.ba.ro {
+		panic("RecordArray is read-only")
+	}
+	if uint(len(rec)) != ra.rsz {
+		panic(fmt.Sprintf("record has wrong size: size %d vs expected %d", len(rec), ra.rsz))
+	}
+	if index >= ra.Len() {
+		return io.EOF
+	}
+
+	rsz := uint64(ra.rsz)
+	offset := index * rsz
+	pageStart, offsetInPage := ra.ba.compute(offset)
+	page, found := ra.ba.cache[pageStart]
+	if found {
+		copy(page.data[offsetInPage:offsetInPage+rsz], rec)
+	}
+
+	_, err := ra.ba.writeAt(rec, int64(ra.ba.base+offset))
+	return err
+}
+
+func (ra *onDiskRecordArray) Iterate(i, j uint64) RecordIterator {
+	if i > j {
+		panic(fmt.Errorf("onDiskRecordArray.Iterate: i > j: i=%d j=%d", i, j))
+	}
+	return &onDiskRecordIterator{
+		ra:   ra,
+		base: i,
+		num:  (j - i),
+	}
+}
+
+func (ra *onDiskRecordArray) ReverseIterate(i, j uint64) RecordIterator {
+	if i > j {
+		panic(fmt.Errorf("onDiskRecordArray.ReverseIterate: i > j: i=%d j=%d", i, j))
+	}
+	return &onDiskRecordIterator{
+		ra:   ra,
+		base: i,
+		num:  (j - i),
+		down: true,
+	}
+}
+
+func (ra *onDiskRecordArray) Truncate(n uint64) error {
+	if ra.ba.ro {
+		panic("RecordArray is read-only")
+	}
+	if n > ra.Len() {
+		panic("cannot grow a record array")
+	}
+	return ra.ba.Truncate(n * uint64(ra.rsz))
+}
+
+func (ra *onDiskRecordArray) Freeze() error {
+	return ra.ba.Freeze()
+}
+
+func (ra *onDiskRecordArray) Flush() error {
+	return ra.ba.Flush()
+}
+
+func (ra *onDiskRecordArray) Sync() error {
+	return ra.ba.Sync()
+}
+
+func (ra *onDiskRecordArray) Close() error {
+	return ra.ba.Close()
+}
+
+var _ RecordArray = (*onDiskRecordArray)(nil)
+
+type onDiskRecordIterator struct {
+	ra     *onDiskRecordArray
+	page   *cachePage
+	rec    []byte
+	err    error
+	base   uint64
+	pos    uint64
+	num    uint64
+	primed bool
+	down   bool
+}
+
+func (iter *onDiskRecordIterator) Err() error { return iter.err }
+func (iter *onDiskRecordIterator) Next() bool { return iter.Skip(1) }
+
+func (iter *onDiskRecordIterator) Index() uint64 {
+	if !iter.primed {
+		panic("must call Next() before Index()")
+	}
+	if iter.pos >= iter.num {
+		panic("must not call Index() after Next() returns false")
+	}
+	if iter.down {
+		return iter.base + (iter.num - iter.pos - 1)
+	}
+	return iter.base + iter.pos
+}
+
+func (iter *onDiskRecordIterator) Record() []byte {
+	if !iter.primed {
+		panic("must call Next() before Record()")
+	}
+	if iter.pos >= iter.num {
+		panic("must not call Record() after Next() returns false")
+	}
+	return iter.rec
+}
+
+func (iter *onDiskRecordIterator) SetRecord(rec []byte) {
+	if !iter.primed {
+		panic("must call Next() before SetRecord()")
+	}
+	if iter.pos >= iter.num {
+		panic("must not call SetRecord() after Next() returns false")
+	}
+	if iter.ra.ba.ro {
+		panic("RecordArray is read-only")
+	}
+	if uint(len(rec)) != iter.ra.rsz {
+		panic(fmt.Sprintf("record has wrong size: size %d vs expected %d", len(rec), iter.ra.rsz))
+	}
+	if iter.err != nil {
+		return
+	}
+	copy(iter.rec, rec)
+	iter.page.dirty = true
+}
+
+func (iter *onDiskRecordIterator) Skip(n uint64) bool {
+	if iter.pos > iter.num {
+		panic(fmt.Sprintf("iter.pos=%d iter.num=%d", iter.pos, iter.num))
+	}
+	if n == 0 && !iter.primed {
+		panic("must call Next() before Step(0)")
+	}
+	if iter.err != nil {
+		return false
+	}
+	if !iter.primed {
+		n--
+		iter.primed = true
+	}
+	if n >= (iter.num - iter.pos) {
+		iter.pos = iter.num
+		iter.rec = nil
+		return false
+	}
+
+	iter.pos += n
+
+	ba := iter.ra.ba
+	rsz := uint64(iter.ra.rsz)
+	psz := uint64(ba.psz)
+	offset := ba.base + iter.Index()*rsz
+	pageOffset := (offset / psz) * psz
+
+	page := iter.page
+	if page != nil && page.off != pageOffset {
+		err := flushPage(ba, page)
+		if err != nil {
+			iter.err = err
+			iter.rec = nil
+			return false
+		}
+		ba.disposePage(page)
+		iter.page = nil
+		page = nil
+	}
+	if page == nil {
+		var err error
+		page, err = ba.acquirePage(pageOffset)
+		if err != nil {
+			iter.err = err
+			iter.rec = nil
+			return false
+		}
+		iter.page = page
+	}
+
+	offset -= page.off
+	if offset+rsz > uint64(len(page.data)) {
+		iter.err = io.ErrUnexpectedEOF
+		iter.rec = nil
+		return false
+	}
+	iter.rec = page.data[offset : offset+rsz]
+	return true
+}
+
+func (iter *onDiskRecordIterator) Flush() error {
+	return flushPage(iter.ra.ba, iter.page)
+}
+
+func (iter *onDiskRecordIterator) Close() error {
+	if iter.ra == nil {
+		return iter.err
+	}
+	err := iter.Flush()
+	if iter.err != nil {
+		err = iter.err
+	}
+	iter.ra.ba.disposePage(iter.page)
+	*iter = onDiskRecordIterator{err: ErrClosedIterator}
+	return err
+}
+
+var _ RecordIterator = (*onDiskRecordIterator)(nil)
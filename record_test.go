@@ -0,0 +1,100 @@
+package bigarray
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func RunRecordArrayBasicTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	opts = append(opts,
+		RecordSize(14),
+		PageSize(32),
+		NumValues(20))
+
+	ra, err := NewRecordArray(opts...)
+	if err != nil {
+		t.Errorf("NewRecordArray: error: %v", err)
+		return
+	}
+	defer ra.Close()
+
+	if 20 != ra.Len() {
+		t.Errorf("RecordArray.Len: expected 20, got %d", ra.Len())
+	}
+
+	makeRecord := func(index uint64) []byte {
+		rec := make([]byte, 14)
+		binary.LittleEndian.PutUint32(rec[0:4], uint32(index))
+		binary.LittleEndian.PutUint16(rec[4:6], uint16(index&1))
+		binary.LittleEndian.PutUint64(rec[6:14], index*1000)
+		return rec
+	}
+
+	iter := ra.Iterate(0, ra.Len())
+	n := uint64(0)
+	for iter.Next() {
+		if !bytes.Equal(iter.Record(), make([]byte, 14)) {
+			t.Errorf("RecordIterator.Record %d: expected zeroes, got %x", iter.Index(), iter.Record())
+		}
+		iter.SetRecord(makeRecord(iter.Index()))
+		n++
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("RecordArray.Iterate: error: %v", err)
+	}
+	if n != ra.Len() {
+		t.Errorf("RecordArray.Iterate only produced %d records", n)
+	}
+
+	rec := make([]byte, 14)
+	for i := uint64(0); i < ra.Len(); i++ {
+		if err := ra.RecordAt(i, rec); err != nil {
+			t.Errorf("RecordArray.RecordAt %d: error: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(rec, makeRecord(i)) {
+			t.Errorf("RecordArray.RecordAt %d: expected %x, got %x", i, makeRecord(i), rec)
+		}
+	}
+
+	if err := ra.SetRecordAt(7, makeRecord(70)); err != nil {
+		t.Errorf("RecordArray.SetRecordAt 7: error: %v", err)
+	}
+
+	iter = ra.ReverseIterate(0, ra.Len())
+	n = 0
+	for iter.Next() {
+		n++
+		expect := ra.Len() - n
+		if iter.Index() != expect {
+			t.Errorf("RecordArray.ReverseIterate out of order: expected [%d], got [%d]", expect, iter.Index())
+		}
+		if expect == 7 {
+			expect = 70
+		}
+		if !bytes.Equal(iter.Record(), makeRecord(expect)) {
+			t.Errorf("RecordArray.ReverseIterate [%d]: expected %x, got %x", iter.Index(), makeRecord(expect), iter.Record())
+		}
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("RecordArray.ReverseIterate: error: %v", err)
+	}
+
+	if err := ra.Truncate(5); err != nil {
+		t.Errorf("RecordArray.Truncate: error: %v", err)
+	}
+	if 5 != ra.Len() {
+		t.Errorf("RecordArray.Len after Truncate: expected 5, got %d", ra.Len())
+	}
+}
+
+func TestRecordArray_InMemory(t *testing.T) {
+	RunRecordArrayBasicTests(t)
+}
+
+func TestRecordArray_OnDisk(t *testing.T) {
+	RunRecordArrayBasicTests(t, OnDiskThreshold(0))
+}
@@ -0,0 +1,72 @@
+package bigarray
+
+import (
+	"math/bits"
+)
+
+// Summary holds reductions over the elements of an array.  Null elements are
+// counted in Nulls and skipped by every other field.
+type Summary struct {
+	// Count is the number of non-null elements.
+	Count uint64
+
+	// Nulls is the number of null elements.
+	Nulls uint64
+
+	// Min and Max are the smallest and largest non-null elements, or 0 if
+	// Count is 0.
+	Min uint64
+	Max uint64
+
+	// Sum and SumHi are the low and high 64 bits of the sum of the non-null
+	// elements, which cannot overflow 128 bits.
+	Sum   uint64
+	SumHi uint64
+}
+
+// Summarize computes a Summary of the array in a single forward pass.
+func Summarize(ba BigArray) (Summary, error) {
+	var s Summary
+	s.Min = ^uint64(0)
+	iter := ba.Iterate(0, ba.Len())
+	for iter.Next() {
+		if !iter.Valid() {
+			s.Nulls++
+			continue
+		}
+		value := iter.Value()
+		s.Count++
+		if value < s.Min {
+			s.Min = value
+		}
+		if value > s.Max {
+			s.Max = value
+		}
+		var carry uint64
+		s.Sum, carry = bits.Add64(s.Sum, value, 0)
+		s.SumHi += carry
+	}
+	if err := iter.Close(); err != nil {
+		return Summary{}, err
+	}
+	if s.Count == 0 {
+		s.Min = 0
+	}
+	return s, nil
+}
+
+// Reduce folds (fn) over the non-null elements of the array, in index order,
+// starting from (init).
+func Reduce(ba BigArray, init uint64, fn func(acc, value uint64) uint64) (uint64, error) {
+	acc := init
+	iter := ba.Iterate(0, ba.Len())
+	for iter.Next() {
+		if iter.Valid() {
+			acc = fn(acc, iter.Value())
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return 0, err
+	}
+	return acc, nil
+}
@@ -0,0 +1,60 @@
+package bigarray
+
+import (
+	"testing"
+)
+
+func RunReduceTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	opts = append(opts,
+		Nullable(),
+		BytesPerValue(8),
+		PageSize(16),
+		NumValues(10))
+
+	ba, err := New(opts...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+
+	for _, i := range []uint64{1, 4, 7, 8} {
+		ba.SetValueAt(i, ^uint64(0)-i)
+	}
+
+	s, err := Summarize(ba)
+	if err != nil {
+		t.Errorf("Summarize: error: %v", err)
+	}
+	expect := Summary{
+		Count: 4,
+		Nulls: 6,
+		Min:   ^uint64(0) - 8,
+		Max:   ^uint64(0) - 1,
+		Sum:   ^uint64(0) - 23,
+		SumHi: 3,
+	}
+	if s != expect {
+		t.Errorf("Summarize: expected %+v, got %+v", expect, s)
+	}
+
+	n, err := Reduce(ba, 0, func(acc, value uint64) uint64 { return acc + 1 })
+	if err != nil || n != 4 {
+		t.Errorf("Reduce: expected 4, got %d, err=%v", n, err)
+	}
+
+	empty, err := Summarize(Slice(ba, 2, 4))
+	if err != nil || empty != (Summary{Nulls: 2}) {
+		t.Errorf("Summarize all-null: expected {Nulls:2}, got %+v, err=%v", empty, err)
+	}
+}
+
+func TestReduce_InMemory(t *testing.T) {
+	RunReduceTests(t)
+}
+
+func TestReduce_OnDisk(t *testing.T) {
+	RunReduceTests(t, OnDiskThreshold(0))
+}
@@ -0,0 +1,194 @@
+package bigarray
+
+import (
+	"fmt"
+	"io"
+)
+
+// Reversed returns a view which presents the elements of (ba) in reverse
+// order.  No elements are copied, and writes pass through to (ba).
+//
+// Index 0 of the view is the last element of (ba).  If (ba) is a
+// NullableArray, so is the view.  Truncate, Freeze, and Close affect only the
+// view, as for Slice.
+//
+func Reversed(ba BigArray) BigArray {
+	view := reversedArray{ba: ba, end: ba.Len(), num: ba.Len()}
+	if nba, ok := ba.(NullableArray); ok {
+		return &nullableReversedArray{reversedArray: view, nba: nba}
+	}
+	return &view
+}
+
+// reversedArray maps view index (i) to parent index (end-1-i).
+type reversedArray struct {
+	ba  BigArray
+	end uint64
+	num uint64
+	ro  bool
+}
+
+func (view *reversedArray) Frozen() bool {
+	return view.ro || view.ba.Frozen()
+}
+
+func (view *reversedArray) MaxValue() uint64 {
+	return view.ba.MaxValue()
+}
+
+func (view *reversedArray) Len() uint64 {
+	return view.num
+}
+
+func (view *reversedArray) ValueAt(index uint64) (uint64, error) {
+	if index >= view.num {
+		return ^uint64(0), io.EOF
+	}
+	return view.ba.ValueAt(view.end - 1 - index)
+}
+
+func (view *reversedArray) SetValueAt(index uint64, value uint64) error {
+	if view.ro {
+		panic("BigArray is read-only")
+	}
+	if index >= view.num {
+		return io.EOF
+	}
+	return view.ba.SetValueAt(view.end-1-index, value)
+}
+
+func (view *reversedArray) Iterate(i, j uint64) Iterator {
+	if i > j {
+		panic(fmt.Errorf("reversedArray.Iterate: i > j: i=%d j=%d", i, j))
+	}
+	if j > view.num {
+		panic(fmt.Errorf("reversedArray.Iterate: j out of range: j=%d len=%d", j, view.num))
+	}
+	return &reversedIterator{
+		iter: view.ba.ReverseIterate(view.end-j, view.end-i),
+		end:  view.end,
+		ro:   view.ro,
+	}
+}
+
+func (view *reversedArray) ReverseIterate(i, j uint64) Iterator {
+	if i > j {
+		panic(fmt.Errorf("reversedArray.ReverseIterate: i > j: i=%d j=%d", i, j))
+	}
+	if j > view.num {
+		panic(fmt.Errorf("reversedArray.ReverseIterate: j out of range: j=%d len=%d", j, view.num))
+	}
+	return &reversedIterator{
+		iter: view.ba.Iterate(view.end-j, view.end-i),
+		end:  view.end,
+		ro:   view.ro,
+	}
+}
+
+func (view *reversedArray) CopyFrom(src BigArray) error {
+	if view.Frozen() {
+		panic("BigArray is read-only")
+	}
+	if src.Len() != view.Len() {
+		panic("big arrays are not equal in size")
+	}
+	return copyFromImpl(view, src)
+}
+
+// Truncate shrinks the view.  The parent array is unaffected.
+func (view *reversedArray) Truncate(n uint64) error {
+	if view.ro {
+		panic("BigArray is read-only")
+	}
+	if n > view.num {
+		panic("cannot grow a big array")
+	}
+	view.num = n
+	return nil
+}
+
+// Freeze makes the view read-only.  The parent array is unaffected.
+func (view *reversedArray) Freeze() error {
+	view.ro = true
+	return view.ba.Flush()
+}
+
+func (view *reversedArray) Flush() error {
+	return view.ba.Flush()
+}
+
+func (view *reversedArray) Sync() error {
+	return view.ba.Sync()
+}
+
+// Close flushes any writes made through the view.  The parent array remains
+// open.
+func (view *reversedArray) Close() error {
+	return view.ba.Flush()
+}
+
+func (view *reversedArray) Debug() string {
+	return debugImpl(view)
+}
+
+var _ BigArray = (*reversedArray)(nil)
+
+type nullableReversedArray struct {
+	reversedArray
+	nba NullableArray
+}
+
+func (view *nullableReversedArray) IsNull(index uint64) (bool, error) {
+	if index >= view.num {
+		return true, io.EOF
+	}
+	return view.nba.IsNull(view.end - 1 - index)
+}
+
+func (view *nullableReversedArray) SetNull(index uint64) error {
+	if view.ro {
+		panic("BigArray is read-only")
+	}
+	if index >= view.num {
+		return io.EOF
+	}
+	return view.nba.SetNull(view.end - 1 - index)
+}
+
+func (view *nullableReversedArray) Debug() string {
+	return debugImpl(view)
+}
+
+var _ NullableArray = (*nullableReversedArray)(nil)
+
+type reversedIterator struct {
+	iter Iterator
+	end  uint64
+	ro   bool
+}
+
+func (iter *reversedIterator) Next() bool         { return iter.iter.Next() }
+func (iter *reversedIterator) Skip(n uint64) bool { return iter.iter.Skip(n) }
+func (iter *reversedIterator) Index() uint64      { return iter.end - 1 - iter.iter.Index() }
+func (iter *reversedIterator) Value() uint64      { return iter.iter.Value() }
+func (iter *reversedIterator) Valid() bool        { return iter.iter.Valid() }
+func (iter *reversedIterator) Err() error         { return iter.iter.Err() }
+func (iter *reversedIterator) Flush() error       { return iter.iter.Flush() }
+func (iter *reversedIterator) Close() error       { return iter.iter.Close() }
+
+func (iter *reversedIterator) SetValue(value uint64) {
+	if iter.ro {
+		panic("BigArray is read-only")
+	}
+	iter.iter.SetValue(value)
+}
+
+func (iter *reversedIterator) setNull() {
+	if iter.ro {
+		panic("BigArray is read-only")
+	}
+	iter.iter.(nullSetter).setNull()
+}
+
+var _ Iterator = (*reversedIterator)(nil)
+var _ nullSetter = (*reversedIterator)(nil)
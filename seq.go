@@ -0,0 +1,77 @@
+//go:build go1.23
+
+package bigarray
+
+import (
+	"iter"
+)
+
+// Sequence adapts a BigArray for use with range-over-func loops.
+//
+// The basic usage pattern is:
+//
+//   seq := bigarray.Seq(array)
+//   for index, value := range seq.All() {
+//     ...
+//   }
+//   if err := seq.Err(); err != nil {
+//     ... // handle error
+//   }
+//
+// Each loop opens a fresh Iterator and closes it when the loop finishes,
+// including when the loop body exits early via break or return.
+//
+type Sequence struct {
+	ba  BigArray
+	err error
+}
+
+// Seq returns a Sequence over the given array.
+func Seq(ba BigArray) *Sequence {
+	return &Sequence{ba: ba}
+}
+
+// All returns a sequence of (index, value) pairs over the entire array in the
+// forward direction.
+func (seq *Sequence) All() iter.Seq2[uint64, uint64] {
+	return seq.Range(0, seq.ba.Len())
+}
+
+// Range returns a sequence of (index, value) pairs that starts at index (i)
+// and stops at index (j-1).
+func (seq *Sequence) Range(i, j uint64) iter.Seq2[uint64, uint64] {
+	return func(yield func(uint64, uint64) bool) {
+		seq.err = drainIterator(seq.ba.Iterate(i, j), yield)
+	}
+}
+
+// Backward returns a sequence of (index, value) pairs that starts at index
+// (j-1) and stops at index (i).
+func (seq *Sequence) Backward(i, j uint64) iter.Seq2[uint64, uint64] {
+	return func(yield func(uint64, uint64) bool) {
+		seq.err = drainIterator(seq.ba.ReverseIterate(i, j), yield)
+	}
+}
+
+// Err returns the error, if any, which ended the most recent loop.
+func (seq *Sequence) Err() error {
+	return seq.err
+}
+
+func drainIterator(iter Iterator, yield func(uint64, uint64) bool) error {
+	needClose := true
+	defer func() {
+		if needClose {
+			iter.Close()
+		}
+	}()
+
+	for iter.Next() {
+		if !yield(iter.Index(), iter.Value()) {
+			break
+		}
+	}
+
+	needClose = false
+	return iter.Close()
+}
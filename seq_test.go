@@ -0,0 +1,61 @@
+//go:build go1.23
+
+package bigarray
+
+import (
+	"testing"
+)
+
+func TestSequence(t *testing.T) {
+	for _, odt := range []uint64{0, defaultOnDiskThreshold} {
+		ba, err := New(NumValues(16), BytesPerValue(2), PageSize(8), OnDiskThreshold(odt))
+		if err != nil {
+			t.Fatalf("New: error: %v", err)
+		}
+		for i := uint64(0); i < ba.Len(); i++ {
+			ba.SetValueAt(i, i*3)
+		}
+
+		seq := Seq(ba)
+		n := uint64(0)
+		for index, value := range seq.All() {
+			if index != n || value != n*3 {
+				t.Errorf("All: expected [%d]=%d, got [%d]=%d", n, n*3, index, value)
+			}
+			n++
+		}
+		if err := seq.Err(); err != nil {
+			t.Errorf("All: error: %v", err)
+		}
+		if n != ba.Len() {
+			t.Errorf("All: only produced %d values", n)
+		}
+
+		expect := uint64(11)
+		for index := range seq.Backward(4, 12) {
+			if index != expect {
+				t.Errorf("Backward: expected [%d], got [%d]", expect, index)
+			}
+			if index == 8 {
+				break
+			}
+			expect--
+		}
+		if err := seq.Err(); err != nil {
+			t.Errorf("Backward: error: %v", err)
+		}
+
+		n = 0
+		for range seq.Range(2, 6) {
+			n++
+		}
+		if n != 4 {
+			t.Errorf("Range: expected 4 values, got %d", n)
+		}
+
+		// Close panics if any iterator was left open by the early break.
+		if err := ba.Close(); err != nil {
+			t.Errorf("Close: error: %v", err)
+		}
+	}
+}
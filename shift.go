@@ -0,0 +1,203 @@
+package bigarray
+
+import (
+	"fmt"
+)
+
+// DropPrefix removes the first (n) elements of the array, shifting the rest
+// down so that the element at index (n) becomes index 0.
+//
+// In-memory arrays are resliced; the dropped elements stay allocated, and
+// charged to any MemoryBudget, until the array is next reallocated.  On-disk
+// arrays are re-based: the backing
+// file is left untouched, and element 0 simply starts further into the file.
+// On Linux, temporary backing files also have the dropped bytes collapsed out
+// of the file, when the filesystem supports it, to release the disk space.
+// Nullable arrays drop the same prefix from their nulls.
+//
+// Arrays which are not backed directly by memory or a file, such as views,
+// return *NotImplementedError.
+//
+func DropPrefix(ba BigArray, n uint64) error {
+	if ba.Frozen() {
+		panic("BigArray is read-only")
+	}
+	if n > ba.Len() {
+		panic(fmt.Errorf("DropPrefix: n out of range: n=%d len=%d", n, ba.Len()))
+	}
+
+	impl, unlock := lockImpl(ba)
+	defer unlock()
+	switch x := impl.(type) {
+	case *inMemoryArray8:
+		x.data = x.data[n:]
+	case *inMemoryArray16:
+		x.data = x.data[n:]
+	case *inMemoryArray32:
+		x.data = x.data[n:]
+	case *inMemoryArray64:
+		x.data = x.data[n:]
+	case *onDiskArray:
+		if len(x.cache) != 0 {
+			panic("DropPrefix() with live iterators is undefined behavior")
+		}
+		x.base += n * uint64(x.bpv)
+		x.num -= n
+		if x.doc {
+			x.base -= collapseRange(x.f, x.base)
+		}
+	case *nullableArray:
+		num := x.Len() - n
+		if err := DropPrefix(x.data, n); err != nil {
+			return err
+		}
+		return dropBits(x.bits, n, num)
+	default:
+		return &NotImplementedError{Op: "DropPrefix"}
+	}
+	if x, ok := ba.(*dynamicArray); ok && isInMemory(impl) {
+		x.dropped += n * uint64(bytesPerValueOf(impl))
+	}
+	return resizedImpl(ba)
+}
+
+// DeleteRange removes the elements from index (i) through index (j-1),
+// shifting the tail of the array down to close the gap.
+//
+// The tail is moved with CopyRange, so on-disk arrays are shifted in
+// page-sized blocks.  Deleting a prefix is delegated to DropPrefix.
+//
+func DeleteRange(ba BigArray, i, j uint64) error {
+	checkRange("DeleteRange", ba, i, j)
+	if ba.Frozen() {
+		panic("BigArray is read-only")
+	}
+	if i == j {
+		return nil
+	}
+	if i == 0 {
+		err := DropPrefix(ba, j)
+		if _, ok := err.(*NotImplementedError); !ok {
+			return err
+		}
+	}
+
+	num := ba.Len()
+	if err := CopyRange(ba, i, ba, j, num-j); err != nil {
+		return err
+	}
+	return ba.Truncate(num - (j - i))
+}
+
+// InsertAt inserts the given values before index (i), growing the array and
+// shifting the tail up to make room.  If (i) is equal to Len(), the values
+// are appended.
+//
+// The tail is moved with CopyRange, so on-disk arrays are shifted in
+// page-sized blocks.  Arrays which cannot grow, such as views, return
+// *NotImplementedError.
+//
+func InsertAt(ba BigArray, i uint64, values ...uint64) error {
+	checkRange("InsertAt", ba, i, i)
+	if ba.Frozen() {
+		panic("BigArray is read-only")
+	}
+	k := uint64(len(values))
+	if k == 0 {
+		return nil
+	}
+
+	num := ba.Len()
+	if err := growArray(ba, k); err != nil {
+		return err
+	}
+	if err := CopyRange(ba, i+k, ba, i, num-i); err != nil {
+		return err
+	}
+
+	iter := ba.Iterate(i, i+k)
+	for n := 0; iter.Next(); n++ {
+		iter.SetValue(values[n])
+	}
+	return iter.Close()
+}
+
+// growArray appends (k) zero-valued elements to the array.  A nullable array
+// grows its nulls to match.
+func growArray(ba BigArray, k uint64) error {
+	if x, ok := asNullable(ba); ok {
+		num := x.Len() + k
+		if err := growArray(x.data, k); err != nil {
+			return err
+		}
+		return growArray(x.bits, (num+7)/8-x.bits.Len())
+	}
+	impl, unlock := lockImpl(ba)
+	defer unlock()
+	before := inMemoryBytes(impl)
+	if err := growImpl(impl, k); err != nil {
+		return err
+	}
+	if x, ok := ba.(*dynamicArray); ok && inMemoryBytes(impl) > before {
+		// append reallocated, freeing any prefix left by DropPrefix.
+		x.dropped = 0
+	}
+	return resizedImpl(ba)
+}
+
+// growImpl appends (k) zero-valued elements to the array.
+func growImpl(ba BigArray, k uint64) error {
+	switch x := ba.(type) {
+	case *inMemoryArray8:
+		x.data = append(x.data, make([]byte, k)...)
+	case *inMemoryArray16:
+		x.data = append(x.data, make([]uint16, k)...)
+	case *inMemoryArray32:
+		x.data = append(x.data, make([]uint32, k)...)
+	case *inMemoryArray64:
+		x.data = append(x.data, make([]uint64, k)...)
+	case *onDiskArray:
+		if len(x.cache) != 0 {
+			panic("InsertAt() with live iterators is undefined behavior")
+		}
+		num := x.num + k
+		if err := x.f.Truncate(int64(x.base + num*uint64(x.bpv))); err != nil {
+			return err
+		}
+		x.num = num
+	default:
+		return &NotImplementedError{Op: "InsertAt"}
+	}
+	return nil
+}
+
+// dropBits removes the bits of the first (n) elements from the bitset of a
+// nullable array, leaving the bits of the remaining (num) elements.  Whole
+// bytes are dropped with DropPrefix, and any remaining shift of less than a
+// byte is made in a single forward pass.
+func dropBits(bits BigArray, n, num uint64) error {
+	if err := DropPrefix(bits, n/8); err != nil {
+		return err
+	}
+	if r := n % 8; r != 0 {
+		rd := bits.Iterate(0, bits.Len())
+		wr := bits.Iterate(0, bits.Len())
+		rd.Next()
+		for wr.Next() {
+			lo := rd.Value()
+			hi := uint64(0)
+			if rd.Next() {
+				hi = rd.Value()
+			}
+			wr.SetValue(((lo >> r) | (hi << (8 - r))) & 0xff)
+		}
+		err := wr.Close()
+		if err2 := rd.Close(); err == nil {
+			err = err2
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return bits.Truncate((num + 7) / 8)
+}
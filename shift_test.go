@@ -0,0 +1,150 @@
+package bigarray
+
+import (
+	"testing"
+)
+
+func RunShiftTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	ba, err := New(append(opts, MaxValue(100), PageSize(4), NumValues(10))...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+
+	check := func(op string, expect string) {
+		t.Helper()
+		if actual := ba.Debug(); actual != expect {
+			t.Errorf("%s: expected %s, got %s", op, expect, actual)
+		}
+	}
+
+	for i := uint64(0); i < ba.Len(); i++ {
+		ba.SetValueAt(i, i)
+	}
+
+	if err := DropPrefix(ba, 3); err != nil {
+		t.Errorf("DropPrefix: error: %v", err)
+	}
+	check("DropPrefix", "[3 4 5 6 7 8 9]")
+
+	if err := InsertAt(ba, 2, 50, 51, 52); err != nil {
+		t.Errorf("InsertAt: error: %v", err)
+	}
+	check("InsertAt", "[3 4 50 51 52 5 6 7 8 9]")
+
+	if err := InsertAt(ba, ba.Len(), 60); err != nil {
+		t.Errorf("InsertAt end: error: %v", err)
+	}
+	check("InsertAt end", "[3 4 50 51 52 5 6 7 8 9 60]")
+
+	if err := DeleteRange(ba, 1, 4); err != nil {
+		t.Errorf("DeleteRange: error: %v", err)
+	}
+	check("DeleteRange", "[3 52 5 6 7 8 9 60]")
+
+	if err := DeleteRange(ba, 0, 2); err != nil {
+		t.Errorf("DeleteRange prefix: error: %v", err)
+	}
+	check("DeleteRange prefix", "[5 6 7 8 9 60]")
+
+	if err := InsertAt(ba, 0, 1); err != nil {
+		t.Errorf("InsertAt start: error: %v", err)
+	}
+	check("InsertAt start", "[1 5 6 7 8 9 60]")
+
+	big, err := New(append(opts, MaxValue(1<<20), NumValues(100000))...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer big.Close()
+	iter := big.Iterate(0, big.Len())
+	for iter.Next() {
+		iter.SetValue(iter.Index())
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("Iterator.Close: error: %v", err)
+	}
+	if err := DropPrefix(big, 70000); err != nil {
+		t.Errorf("DropPrefix big: error: %v", err)
+	}
+	if big.Len() != 30000 {
+		t.Errorf("DropPrefix big: expected Len 30000, got %d", big.Len())
+	}
+	iter = big.Iterate(0, big.Len())
+	for iter.Next() {
+		if expect := iter.Index() + 70000; iter.Value() != expect {
+			t.Errorf("DropPrefix big %d: expected %d, got %d", iter.Index(), expect, iter.Value())
+			break
+		}
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("Iterator.Close: error: %v", err)
+	}
+
+	if err := DropPrefix(Slice(big, 0, 10), 1); err == nil {
+		t.Errorf("DropPrefix view: expected error, got nil")
+	}
+}
+
+func RunNullableShiftTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	ba, err := New(append(opts, Nullable(), MaxValue(100), PageSize(4), NumValues(20))...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+	for i := uint64(0); i < ba.Len(); i++ {
+		if i%3 != 0 {
+			ba.SetValueAt(i, i)
+		}
+	}
+
+	check := func(op string, expect string) {
+		t.Helper()
+		if actual := ba.Debug(); actual != expect {
+			t.Errorf("%s: expected %s, got %s", op, expect, actual)
+		}
+	}
+
+	if err := DropPrefix(ba, 3); err != nil {
+		t.Errorf("DropPrefix: error: %v", err)
+	}
+	check("DropPrefix", "[. 4 5 . 7 8 . 10 11 . 13 14 . 16 17 . 19]")
+
+	if err := DeleteRange(ba, 0, 8); err != nil {
+		t.Errorf("DeleteRange prefix: error: %v", err)
+	}
+	check("DeleteRange prefix", "[11 . 13 14 . 16 17 . 19]")
+
+	if err := InsertAt(ba, 2, 70, 71); err != nil {
+		t.Errorf("InsertAt: error: %v", err)
+	}
+	check("InsertAt", "[11 . 70 71 13 14 . 16 17 . 19]")
+
+	if err := DeleteRange(ba, 1, 4); err != nil {
+		t.Errorf("DeleteRange: error: %v", err)
+	}
+	check("DeleteRange", "[11 13 14 . 16 17 . 19]")
+}
+
+func TestNullableShift_InMemory(t *testing.T) {
+	RunNullableShiftTests(t)
+}
+
+func TestNullableShift_OnDisk(t *testing.T) {
+	RunNullableShiftTests(t, OnDiskThreshold(0))
+}
+
+func TestShift_InMemory(t *testing.T) {
+	RunShiftTests(t)
+}
+
+func TestShift_OnDisk(t *testing.T) {
+	RunShiftTests(t, OnDiskThreshold(0))
+}
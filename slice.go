@@ -0,0 +1,217 @@
+package bigarray
+
+import (
+	"fmt"
+	"io"
+)
+
+// Slice returns a view of the elements of (ba) from index (i) through index
+// (j-1).  The view shares storage with (ba): no elements are copied, and
+// writes through either one are visible in the other.
+//
+// Index 0 of the view is index (i) of (ba).  If (ba) is a NullableArray, so
+// is the view.  A slice of a frozen view is also frozen.
+//
+// Truncate, Freeze, and Close affect only the view, never the parent.  In
+// particular, closing a view does not close (ba), and (ba) must not be closed
+// while any of its views are still in use.
+//
+func Slice(ba BigArray, i, j uint64) BigArray {
+	if i > j {
+		panic(fmt.Errorf("Slice: i > j: i=%d j=%d", i, j))
+	}
+	if j > ba.Len() {
+		panic(fmt.Errorf("Slice: j out of range: j=%d len=%d", j, ba.Len()))
+	}
+	ro := false
+	if x, ok := ba.(*sliceArray); ok {
+		ba = x.ba
+		i += x.base
+		j += x.base
+		ro = x.ro
+	} else if x, ok := ba.(*nullableSliceArray); ok {
+		ba = x.ba
+		i += x.base
+		j += x.base
+		ro = x.ro
+	}
+	view := sliceArray{ba: ba, base: i, num: (j - i), ro: ro}
+	if nba, ok := ba.(NullableArray); ok {
+		return &nullableSliceArray{sliceArray: view, nba: nba}
+	}
+	return &view
+}
+
+type sliceArray struct {
+	ba   BigArray
+	base uint64
+	num  uint64
+	ro   bool
+}
+
+func (view *sliceArray) Frozen() bool {
+	return view.ro || view.ba.Frozen()
+}
+
+func (view *sliceArray) MaxValue() uint64 {
+	return view.ba.MaxValue()
+}
+
+func (view *sliceArray) Len() uint64 {
+	return view.num
+}
+
+func (view *sliceArray) ValueAt(index uint64) (uint64, error) {
+	if index >= view.num {
+		return ^uint64(0), io.EOF
+	}
+	return view.ba.ValueAt(view.base + index)
+}
+
+func (view *sliceArray) SetValueAt(index uint64, value uint64) error {
+	if view.ro {
+		panic("BigArray is read-only")
+	}
+	if index >= view.num {
+		return io.EOF
+	}
+	return view.ba.SetValueAt(view.base+index, value)
+}
+
+func (view *sliceArray) Iterate(i, j uint64) Iterator {
+	if i > j {
+		panic(fmt.Errorf("sliceArray.Iterate: i > j: i=%d j=%d", i, j))
+	}
+	if j > view.num {
+		panic(fmt.Errorf("sliceArray.Iterate: j out of range: j=%d len=%d", j, view.num))
+	}
+	return &sliceIterator{
+		iter: view.ba.Iterate(view.base+i, view.base+j),
+		base: view.base,
+		ro:   view.ro,
+	}
+}
+
+func (view *sliceArray) ReverseIterate(i, j uint64) Iterator {
+	if i > j {
+		panic(fmt.Errorf("sliceArray.ReverseIterate: i > j: i=%d j=%d", i, j))
+	}
+	if j > view.num {
+		panic(fmt.Errorf("sliceArray.ReverseIterate: j out of range: j=%d len=%d", j, view.num))
+	}
+	return &sliceIterator{
+		iter: view.ba.ReverseIterate(view.base+i, view.base+j),
+		base: view.base,
+		ro:   view.ro,
+	}
+}
+
+func (view *sliceArray) CopyFrom(src BigArray) error {
+	if view.Frozen() {
+		panic("BigArray is read-only")
+	}
+	if src.Len() != view.Len() {
+		panic("big arrays are not equal in size")
+	}
+	return copyFromImpl(view, src)
+}
+
+// Truncate shrinks the view.  The parent array is unaffected.
+func (view *sliceArray) Truncate(n uint64) error {
+	if view.ro {
+		panic("BigArray is read-only")
+	}
+	if n > view.num {
+		panic("cannot grow a big array")
+	}
+	view.num = n
+	return nil
+}
+
+// Freeze makes the view read-only.  The parent array is unaffected.
+func (view *sliceArray) Freeze() error {
+	view.ro = true
+	return view.ba.Flush()
+}
+
+func (view *sliceArray) Flush() error {
+	return view.ba.Flush()
+}
+
+func (view *sliceArray) Sync() error {
+	return view.ba.Sync()
+}
+
+// Close flushes any writes made through the view.  The parent array remains
+// open.
+func (view *sliceArray) Close() error {
+	return view.ba.Flush()
+}
+
+func (view *sliceArray) Debug() string {
+	return debugImpl(view)
+}
+
+var _ BigArray = (*sliceArray)(nil)
+
+type nullableSliceArray struct {
+	sliceArray
+	nba NullableArray
+}
+
+func (view *nullableSliceArray) IsNull(index uint64) (bool, error) {
+	if index >= view.num {
+		return true, io.EOF
+	}
+	return view.nba.IsNull(view.base + index)
+}
+
+func (view *nullableSliceArray) SetNull(index uint64) error {
+	if view.ro {
+		panic("BigArray is read-only")
+	}
+	if index >= view.num {
+		return io.EOF
+	}
+	return view.nba.SetNull(view.base + index)
+}
+
+func (view *nullableSliceArray) Debug() string {
+	return debugImpl(view)
+}
+
+var _ NullableArray = (*nullableSliceArray)(nil)
+
+// sliceIterator translates the indices of an Iterator over the parent array
+// into indices relative to the view.
+type sliceIterator struct {
+	iter Iterator
+	base uint64
+	ro   bool
+}
+
+func (iter *sliceIterator) Next() bool         { return iter.iter.Next() }
+func (iter *sliceIterator) Skip(n uint64) bool { return iter.iter.Skip(n) }
+func (iter *sliceIterator) Index() uint64      { return iter.iter.Index() - iter.base }
+func (iter *sliceIterator) Value() uint64      { return iter.iter.Value() }
+func (iter *sliceIterator) Valid() bool        { return iter.iter.Valid() }
+func (iter *sliceIterator) Err() error         { return iter.iter.Err() }
+func (iter *sliceIterator) Flush() error       { return iter.iter.Flush() }
+func (iter *sliceIterator) Close() error       { return iter.iter.Close() }
+
+func (iter *sliceIterator) SetValue(value uint64) {
+	if iter.ro {
+		panic("BigArray is read-only")
+	}
+	iter.iter.SetValue(value)
+}
+
+func (iter *sliceIterator) setNull() {
+	if iter.ro {
+		panic("BigArray is read-only")
+	}
+	iter.iter.(nullSetter).setNull()
+}
+
+var _ Iterator = (*sliceIterator)(nil)
+var _ nullSetter = (*sliceIterator)(nil)
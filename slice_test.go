@@ -0,0 +1,80 @@
+package bigarray
+
+import (
+	"testing"
+)
+
+func RunSliceTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	ba, err := New(append(opts, MaxValue(1000), PageSize(16), NumValues(30))...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+
+	for i := uint64(0); i < ba.Len(); i++ {
+		ba.SetValueAt(i, i)
+	}
+
+	view := Slice(ba, 5, 25)
+	if view.Len() != 20 {
+		t.Errorf("Slice.Len: expected 20, got %d", view.Len())
+	}
+	if value, err := view.ValueAt(0); err != nil || value != 5 {
+		t.Errorf("Slice.ValueAt 0: expected 5, got %d, %v", value, err)
+	}
+
+	inner := Slice(view, 10, 20)
+	iter := inner.Iterate(0, inner.Len())
+	n := uint64(0)
+	for iter.Next() {
+		if iter.Index() != n || iter.Value() != 15+n {
+			t.Errorf("Slice.Iterate: expected [%d]=%d, got [%d]=%d", n, 15+n, iter.Index(), iter.Value())
+		}
+		iter.SetValue(iter.Value() * 10)
+		n++
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("Slice.Iterate: error: %v", err)
+	}
+
+	if value, err := ba.ValueAt(17); err != nil || value != 170 {
+		t.Errorf("BigArray.ValueAt 17: expected 170, got %d, %v", value, err)
+	}
+
+	if err := view.Truncate(3); err != nil {
+		t.Errorf("Slice.Truncate: error: %v", err)
+	}
+	if view.Len() != 3 || ba.Len() != 30 {
+		t.Errorf("Slice.Truncate: expected lengths 3 and 30, got %d and %d", view.Len(), ba.Len())
+	}
+	const expectDebug = "[5 6 7]"
+	if actual := view.Debug(); actual != expectDebug {
+		t.Errorf("Slice.Debug: expected %s, got %s", expectDebug, actual)
+	}
+	if err := view.Freeze(); err != nil {
+		t.Errorf("Slice.Freeze: error: %v", err)
+	}
+	if inner := Slice(view, 1, 2); !inner.Frozen() {
+		t.Errorf("Slice of a frozen Slice: expected Frozen")
+	}
+	if ba.Frozen() {
+		t.Errorf("Slice.Freeze: parent was frozen too")
+	}
+	if err := view.Close(); err != nil {
+		t.Errorf("Slice.Close: error: %v", err)
+	}
+	if _, err := ba.ValueAt(29); err != nil {
+		t.Errorf("BigArray.ValueAt after Slice.Close: error: %v", err)
+	}
+}
+
+func TestSlice_InMemory(t *testing.T) {
+	RunSliceTests(t)
+}
+
+func TestSlice_OnDisk(t *testing.T) {
+	RunSliceTests(t, OnDiskThreshold(0))
+}
@@ -0,0 +1,162 @@
+package bigarray
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Stat identifies one of the I/O counters kept by on-disk arrays.
+type Stat uint8
+
+const (
+	// StatPageAcquires counts calls to acquire a page of the file.
+	StatPageAcquires Stat = iota
+
+	// StatCacheHits counts page acquisitions satisfied by a live page.
+	StatCacheHits
+
+	// StatCacheMisses counts page acquisitions which read from the file.
+	StatCacheMisses
+
+	// StatReadCalls counts ReadAt calls on the backing file.
+	StatReadCalls
+
+	// StatBytesRead counts bytes read from the backing file.
+	StatBytesRead
+
+	// StatWriteCalls counts WriteAt calls on the backing file.
+	StatWriteCalls
+
+	// StatBytesWritten counts bytes written to the backing file.
+	StatBytesWritten
+
+	// StatFlushes counts calls to Flush.
+	StatFlushes
+
+	// StatDirtyEvictions counts dirty pages written back because an Iterator
+	// moved off them or was closed.
+	StatDirtyEvictions
+
+	// StatPoolHits counts page buffers taken from the WithPool pool.
+	StatPoolHits
+
+	// StatPoolMisses counts page buffers allocated with make, because there
+	// was no pool or the pool's buffer was too small.
+	StatPoolMisses
+
+	numStats
+)
+
+var statNames = [numStats]string{
+	"PageAcquires",
+	"CacheHits",
+	"CacheMisses",
+	"ReadCalls",
+	"BytesRead",
+	"WriteCalls",
+	"BytesWritten",
+	"Flushes",
+	"DirtyEvictions",
+	"PoolHits",
+	"PoolMisses",
+}
+
+func (stat Stat) String() string {
+	if stat < numStats {
+		return statNames[stat]
+	}
+	return fmt.Sprintf("Stat(%d)", uint8(stat))
+}
+
+// Stats is a snapshot of the I/O counters of an array.
+type Stats struct {
+	PageAcquires   uint64
+	CacheHits      uint64
+	CacheMisses    uint64
+	ReadCalls      uint64
+	BytesRead      uint64
+	WriteCalls     uint64
+	BytesWritten   uint64
+	Flushes        uint64
+	DirtyEvictions uint64
+	PoolHits       uint64
+	PoolMisses     uint64
+}
+
+func (s Stats) add(t Stats) Stats {
+	s.PageAcquires += t.PageAcquires
+	s.CacheHits += t.CacheHits
+	s.CacheMisses += t.CacheMisses
+	s.ReadCalls += t.ReadCalls
+	s.BytesRead += t.BytesRead
+	s.WriteCalls += t.WriteCalls
+	s.BytesWritten += t.BytesWritten
+	s.Flushes += t.Flushes
+	s.DirtyEvictions += t.DirtyEvictions
+	s.PoolHits += t.PoolHits
+	s.PoolMisses += t.PoolMisses
+	return s
+}
+
+// Observer receives every change to an array's I/O counters, e.g. to forward
+// them to a metrics system.  Observe is called synchronously from the
+// goroutine which is using the array, so it should be cheap.
+type Observer interface {
+	Observe(stat Stat, delta uint64)
+}
+
+// StatsOf returns a snapshot of the I/O counters of (ba).  In-memory arrays
+// and views do no I/O of their own, and return zero Stats.
+//
+// StatsOf may be called concurrently with the array's use, e.g. from a
+// metrics exporter.
+//
+func StatsOf(ba BigArray) Stats {
+	if x, ok := ba.(interface{ Stats() Stats }); ok {
+		return x.Stats()
+	}
+	return Stats{}
+}
+
+// ioStats holds the counters for one on-disk array.  It is shared with any
+// array which replaces it, e.g. when an AutoWiden array is widened.
+type ioStats struct {
+	counters [numStats]uint64
+	obs      Observer
+}
+
+func newIOStats(obs Observer) *ioStats {
+	return &ioStats{obs: obs}
+}
+
+func (st *ioStats) add(stat Stat, delta uint64) {
+	if st == nil {
+		return
+	}
+	atomic.AddUint64(&st.counters[stat], delta)
+	if st.obs != nil {
+		st.obs.Observe(stat, delta)
+	}
+}
+
+func (st *ioStats) snapshot() Stats {
+	if st == nil {
+		return Stats{}
+	}
+	load := func(stat Stat) uint64 {
+		return atomic.LoadUint64(&st.counters[stat])
+	}
+	return Stats{
+		PageAcquires:   load(StatPageAcquires),
+		CacheHits:      load(StatCacheHits),
+		CacheMisses:    load(StatCacheMisses),
+		ReadCalls:      load(StatReadCalls),
+		BytesRead:      load(StatBytesRead),
+		WriteCalls:     load(StatWriteCalls),
+		BytesWritten:   load(StatBytesWritten),
+		Flushes:        load(StatFlushes),
+		DirtyEvictions: load(StatDirtyEvictions),
+		PoolHits:       load(StatPoolHits),
+		PoolMisses:     load(StatPoolMisses),
+	}
+}
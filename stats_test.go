@@ -0,0 +1,93 @@
+package bigarray
+
+import (
+	"sync"
+	"testing"
+)
+
+type recordingObserver struct {
+	counts [numStats]uint64
+}
+
+func (obs *recordingObserver) Observe(stat Stat, delta uint64) {
+	obs.counts[stat] += delta
+}
+
+func TestStats_OnDisk(t *testing.T) {
+	pool := &sync.Pool{New: func() interface{} { return make([]byte, 16) }}
+	obs := &recordingObserver{}
+	ba, err := New(
+		MaxValue(255),
+		NumValues(64),
+		PageSize(16),
+		OnDiskThreshold(0),
+		WithPool(pool),
+		WithObserver(obs))
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+
+	iter := ba.Iterate(0, ba.Len())
+	for iter.Next() {
+		iter.SetValue(iter.Index())
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("Iterator.Close: error: %v", err)
+	}
+	if _, err := ba.ValueAt(5); err != nil {
+		t.Errorf("BigArray.ValueAt 5: error: %v", err)
+	}
+	if err := ba.Flush(); err != nil {
+		t.Errorf("BigArray.Flush: error: %v", err)
+	}
+
+	expect := Stats{
+		PageAcquires:   4,
+		CacheMisses:    4,
+		ReadCalls:      5,
+		BytesRead:      65,
+		WriteCalls:     4,
+		BytesWritten:   64,
+		Flushes:        1,
+		DirtyEvictions: 4,
+		PoolHits:       4,
+	}
+	if actual := StatsOf(ba); actual != expect {
+		t.Errorf("StatsOf: expected %+v, got %+v", expect, actual)
+	}
+
+	r1 := ba.Iterate(0, 16)
+	r2 := ba.Iterate(0, 16)
+	r1.Next()
+	r2.Next()
+	r1.Close()
+	r2.Close()
+	stats := StatsOf(ba)
+	if stats.PageAcquires != 6 || stats.CacheHits != 1 || stats.CacheMisses != 5 {
+		t.Errorf("StatsOf: expected 6 acquires, 1 hit, 5 misses, got %+v", stats)
+	}
+
+	for stat := Stat(0); stat < numStats; stat++ {
+		if obs.counts[stat] == 0 && stat != StatPoolMisses {
+			t.Errorf("Observer: no updates to %v", stat)
+		}
+	}
+	if obs.counts[StatPageAcquires] != stats.PageAcquires {
+		t.Errorf("Observer: expected %d PageAcquires, got %d", stats.PageAcquires, obs.counts[StatPageAcquires])
+	}
+}
+
+func TestStats_InMemory(t *testing.T) {
+	ba, err := New(MaxValue(255), NumValues(64))
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+	ba.SetValueAt(1, 2)
+	if actual := StatsOf(ba); actual != (Stats{}) {
+		t.Errorf("StatsOf: expected zero Stats, got %+v", actual)
+	}
+}
@@ -0,0 +1,21 @@
+package bigarray
+
+import (
+	"os"
+	"syscall"
+)
+
+// fdatasync commits the data of (f), and only as much metadata as is needed
+// to read the data back, to stable storage.
+func fdatasync(f *os.File) error {
+	for {
+		err := syscall.Fdatasync(int(f.Fd()))
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return &os.PathError{Op: "fdatasync", Path: f.Name(), Err: err}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package bigarray
+
+import (
+	"os"
+)
+
+// fdatasync falls back to a full fsync on platforms without fdatasync.
+func fdatasync(f *os.File) error {
+	return f.Sync()
+}
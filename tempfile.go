@@ -0,0 +1,125 @@
+package bigarray
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// defaultTempPrefix is the default for TempPrefix.
+const defaultTempPrefix = "bigarray"
+
+// tempSuffix ends the name of every named temporary file, so that
+// CleanupOrphans can recognize files created by this package.
+const tempSuffix = ".bigarray"
+
+// createTempFile creates a temporary file of (numBytes) bytes, which is
+// deleted by removeFile.
+//
+// Where the platform supports it, the file is created without a name, so
+// that it vanishes when it is closed or the process dies.  Otherwise the file
+// is named "<prefix>-<pid>-<random>.bigarray", recording the PID of its owner
+// for CleanupOrphans.
+func createTempFile(o options, numBytes uint64) (File, error) {
+	file, err := openUnnamedFile(o.tempDir)
+	if err != nil {
+		file, err = ioutil.TempFile(o.tempDir, tempPattern(o.tempPrefix, os.Getpid()))
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = file.Truncate(int64(numBytes))
+	if err != nil {
+		removeFile(file)
+		return nil, err
+	}
+	return file, nil
+}
+
+// removeFile closes and deletes a file returned by createTempFile.  Unnamed
+// files have the name "", and need only be closed.
+func removeFile(file File) error {
+	type namer interface{ Name() string }
+	name := file.(namer).Name()
+	if name == "" {
+		return file.Close()
+	}
+	if err := os.Remove(name); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func tempPattern(prefix string, pid int) string {
+	if prefix == "" {
+		prefix = defaultTempPrefix
+	}
+	return fmt.Sprintf("%s-%d-*%s", prefix, pid, tempSuffix)
+}
+
+// CleanupOrphans deletes temporary files left in (dir) by processes which
+// have exited without removing them, e.g. because they were killed.  The
+// default, or "", is os.TempDir().  TempPrefix may be given to match the
+// option used to create the files; other options are ignored.
+//
+// A file is deleted only if its name matches the pattern used for temporary
+// files and the PID recorded in the name is not a running process.  The
+// names of the deleted files are returned.  Files created without a name, as
+// on Linux, never need to be cleaned up.
+//
+func CleanupOrphans(dir string, opts ...Option) ([]string, error) {
+	var o options
+	o.apply(opts...)
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	prefix := o.tempPrefix
+	if prefix == "" {
+		prefix = defaultTempPrefix
+	}
+	re := regexp.MustCompile(`^` + regexp.QuoteMeta(prefix) + `-(\d+)-.*` + regexp.QuoteMeta(tempSuffix) + `$`)
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	var finalError error
+	for _, info := range infos {
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		m := re.FindStringSubmatch(info.Name())
+		if m == nil {
+			continue
+		}
+		pid, err := strconv.Atoi(m[1])
+		if err != nil || pid == os.Getpid() || processAlive(pid) {
+			continue
+		}
+		path := filepath.Join(dir, info.Name())
+		if err := os.Remove(path); err != nil {
+			if finalError == nil {
+				finalError = err
+			}
+			continue
+		}
+		removed = append(removed, path)
+	}
+	return removed, finalError
+}
+
+// processAlive returns false if (pid) is known not to be a running process.
+// When in doubt, e.g. on platforms which cannot probe a process, it returns
+// true so that the process's files are left alone.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return true
+	}
+	return processExists(pid)
+}
@@ -0,0 +1,26 @@
+//go:build !sparc64
+// +build !sparc64
+
+package bigarray
+
+import (
+	"os"
+	"syscall"
+)
+
+// oTmpfile is O_TMPFILE, which the syscall package does not define.
+const oTmpfile = 0x400000 | syscall.O_DIRECTORY
+
+// openUnnamedFile creates a file in (dir) which has no name, so that it is
+// deleted automatically when it is closed, even if the process is killed.
+// It fails if the kernel or filesystem does not support O_TMPFILE.
+func openUnnamedFile(dir string) (*os.File, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	fd, err := syscall.Open(dir, oTmpfile|syscall.O_RDWR|syscall.O_CLOEXEC, 0600)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: dir, Err: err}
+	}
+	return os.NewFile(uintptr(fd), ""), nil
+}
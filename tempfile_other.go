@@ -0,0 +1,14 @@
+//go:build !linux || sparc64
+// +build !linux sparc64
+
+package bigarray
+
+import (
+	"os"
+)
+
+// openUnnamedFile always fails on this platform, which has no equivalent to
+// Linux's O_TMPFILE.
+func openUnnamedFile(dir string) (*os.File, error) {
+	return nil, &NotImplementedError{Op: "openUnnamedFile"}
+}
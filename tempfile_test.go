@@ -0,0 +1,97 @@
+package bigarray
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"testing"
+)
+
+func TestTempDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tempdir")
+	if err != nil {
+		t.Fatalf("TempDir: error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ba, err := New(
+		MaxValue(255),
+		NumValues(64),
+		OnDiskThreshold(0),
+		TempDir(dir),
+		TempPrefix("test"))
+	if err != nil {
+		t.Fatalf("New: error: %v", err)
+	}
+
+	names, _ := filepath.Glob(filepath.Join(dir, "*"))
+	re := regexp.MustCompile(fmt.Sprintf(`/test-%d-[^/]*\.bigarray$`, os.Getpid()))
+	switch {
+	case len(names) == 0:
+		// The file was created without a name.
+	case len(names) == 1 && re.MatchString(filepath.ToSlash(names[0])):
+	default:
+		t.Errorf("New: unexpected files in TempDir: %q", names)
+	}
+
+	if err := ba.Close(); err != nil {
+		t.Errorf("BigArray.Close: error: %v", err)
+	}
+	names, _ = filepath.Glob(filepath.Join(dir, "*"))
+	if len(names) != 0 {
+		t.Errorf("BigArray.Close: files left in TempDir: %q", names)
+	}
+}
+
+func TestCleanupOrphans(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orphans")
+	if err != nil {
+		t.Fatalf("TempDir: error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("exec: error: %v", err)
+	}
+	dead := cmd.Process.Pid
+
+	names := []string{
+		fmt.Sprintf("bigarray-%d-1.bigarray", dead),
+		fmt.Sprintf("bigarray-%d-2.bigarray", os.Getpid()),
+		fmt.Sprintf("custom-%d-3.bigarray", dead),
+		fmt.Sprintf("bigarray-%d-4.tmp", dead),
+	}
+	for _, name := range names {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatalf("WriteFile: error: %v", err)
+		}
+	}
+
+	removed, err := CleanupOrphans(dir)
+	if err != nil {
+		t.Errorf("CleanupOrphans: error: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != filepath.Join(dir, names[0]) {
+		t.Errorf("CleanupOrphans: expected [%s], got %q", names[0], removed)
+	}
+
+	removed, err = CleanupOrphans(dir, TempPrefix("custom"))
+	if err != nil {
+		t.Errorf("CleanupOrphans: error: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != filepath.Join(dir, names[2]) {
+		t.Errorf("CleanupOrphans: expected [%s], got %q", names[2], removed)
+	}
+
+	left, _ := filepath.Glob(filepath.Join(dir, "*"))
+	sort.Strings(left)
+	expect := []string{filepath.Join(dir, names[1]), filepath.Join(dir, names[3])}
+	if fmt.Sprint(left) != fmt.Sprint(expect) {
+		t.Errorf("CleanupOrphans: expected %q to remain, got %q", expect, left)
+	}
+}
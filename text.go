@@ -0,0 +1,147 @@
+package bigarray
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type textOptions struct {
+	delim     string
+	buildOpts []Option
+	hasIndex  bool
+	isHex     bool
+}
+
+func (o *textOptions) apply(opts ...TextOption) {
+	o.delim = ","
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// TextOption is a behavior customization for WriteText and ReadText.
+type TextOption func(*textOptions)
+
+// IndexColumn specifies that each line holds the element's index, followed by
+// the delimiter, followed by the element's value.
+//
+// ReadText returns an error if the indices are not 0, 1, 2, and so on.
+//
+func IndexColumn() TextOption {
+	return func(o *textOptions) { o.hasIndex = true }
+}
+
+// Delimiter specifies the string which separates the index column from the
+// value.  The default is ",".
+func Delimiter(delim string) TextOption {
+	return func(o *textOptions) { o.delim = delim }
+}
+
+// Hex specifies that values are written in hexadecimal with a "0x" prefix,
+// rather than in decimal.  ReadText accepts hexadecimal values with or
+// without the prefix.  Indices are always decimal.
+func Hex() TextOption {
+	return func(o *textOptions) { o.isHex = true }
+}
+
+// BuildOptions specifies the options which ReadText passes to NewBuilder.
+func BuildOptions(opts ...Option) TextOption {
+	return func(o *textOptions) { o.buildOpts = append(o.buildOpts, opts...) }
+}
+
+// WriteText writes the array to (w) as newline-delimited text, one element
+// per line.  Unlike Debug, the elements are streamed from an Iterator, so
+// arrays of any size can be written.
+func WriteText(w io.Writer, ba BigArray, opts ...TextOption) error {
+	var o textOptions
+	o.apply(opts...)
+
+	bw := bufio.NewWriter(w)
+	var line []byte
+	iter := ba.Iterate(0, ba.Len())
+	for iter.Next() {
+		line = line[:0]
+		if o.hasIndex {
+			line = strconv.AppendUint(line, iter.Index(), 10)
+			line = append(line, o.delim...)
+		}
+		if o.isHex {
+			line = append(line, "0x"...)
+			line = strconv.AppendUint(line, iter.Value(), 16)
+		} else {
+			line = strconv.AppendUint(line, iter.Value(), 10)
+		}
+		line = append(line, '\n')
+		if _, err := bw.Write(line); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ReadText constructs a new frozen array from newline-delimited text read
+// from (r), in the format written by WriteText.  Blank lines are ignored, as
+// is leading and trailing whitespace on each line.
+//
+// The array is built with a Builder, so the input may be of any length and
+// the width of the array is chosen automatically.  Use BuildOptions to
+// customize the Builder.
+//
+func ReadText(r io.Reader, opts ...TextOption) (BigArray, error) {
+	var o textOptions
+	o.apply(opts...)
+
+	b := NewBuilder(o.buildOpts...)
+	defer b.Close()
+
+	delim := []byte(o.delim)
+	base := 10
+	if o.isHex {
+		base = 16
+	}
+
+	sc := bufio.NewScanner(r)
+	for lineno := 1; sc.Scan(); lineno++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if o.hasIndex {
+			k := bytes.Index(line, delim)
+			if k < 0 || len(delim) == 0 {
+				return nil, fmt.Errorf("line %d: missing delimiter %q", lineno, o.delim)
+			}
+			index, err := strconv.ParseUint(string(bytes.TrimSpace(line[0:k])), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineno, err)
+			}
+			if index != b.Len() {
+				return nil, fmt.Errorf("line %d: expected index %d, got %d", lineno, b.Len(), index)
+			}
+			line = bytes.TrimSpace(line[k+len(delim):])
+		}
+
+		if o.isHex && len(line) > 2 && line[0] == '0' && (line[1] == 'x' || line[1] == 'X') {
+			line = line[2:]
+		}
+		value, err := strconv.ParseUint(string(line), base, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+		if err := b.Add(value); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
@@ -0,0 +1,83 @@
+package bigarray
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func RunTextTests(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	ba, err := New(append(opts, MaxValue(1000), NumValues(4))...)
+	if err != nil {
+		t.Errorf("New: error: %v", err)
+		return
+	}
+	defer ba.Close()
+	for i := uint64(0); i < ba.Len(); i++ {
+		ba.SetValueAt(i, i*250)
+	}
+
+	type testrow struct {
+		name   string
+		opts   []TextOption
+		expect string
+	}
+
+	testdata := []testrow{
+		{"Plain", nil, "0\n250\n500\n750\n"},
+		{"Hex", []TextOption{Hex()}, "0x0\n0xfa\n0x1f4\n0x2ee\n"},
+		{"Index", []TextOption{IndexColumn()}, "0,0\n1,250\n2,500\n3,750\n"},
+		{"IndexTabHex", []TextOption{IndexColumn(), Delimiter("\t"), Hex()}, "0\t0x0\n1\t0xfa\n2\t0x1f4\n3\t0x2ee\n"},
+	}
+
+	for _, row := range testdata {
+		var buf bytes.Buffer
+		if err := WriteText(&buf, ba, row.opts...); err != nil {
+			t.Errorf("%s: WriteText: error: %v", row.name, err)
+			continue
+		}
+		if actual := buf.String(); actual != row.expect {
+			t.Errorf("%s: WriteText: expected %q, got %q", row.name, row.expect, actual)
+		}
+
+		dup, err := ReadText(&buf, append(row.opts, BuildOptions(opts...))...)
+		if err != nil {
+			t.Errorf("%s: ReadText: error: %v", row.name, err)
+			continue
+		}
+		if expect, actual := ba.Debug(), dup.Debug(); actual != expect {
+			t.Errorf("%s: ReadText: expected %s, got %s", row.name, expect, actual)
+		}
+		if expect, actual := uint64(750), dup.MaxValue(); actual != expect {
+			t.Errorf("%s: ReadText: expected MaxValue %d, got %d", row.name, expect, actual)
+		}
+		dup.Close()
+	}
+
+	dup, err := ReadText(strings.NewReader("  7 \r\n\n8\n"), BuildOptions(opts...))
+	if err != nil {
+		t.Errorf("ReadText: error: %v", err)
+	} else {
+		if expect, actual := "[7 8]", dup.Debug(); actual != expect {
+			t.Errorf("ReadText: expected %s, got %s", expect, actual)
+		}
+		dup.Close()
+	}
+
+	if _, err := ReadText(strings.NewReader("1\nx\n"), BuildOptions(opts...)); err == nil {
+		t.Errorf("ReadText: expected error for bad value, got nil")
+	}
+	if _, err := ReadText(strings.NewReader("0,1\n2,3\n"), IndexColumn(), BuildOptions(opts...)); err == nil {
+		t.Errorf("ReadText: expected error for bad index, got nil")
+	}
+}
+
+func TestText_InMemory(t *testing.T) {
+	RunTextTests(t)
+}
+
+func TestText_OnDisk(t *testing.T) {
+	RunTextTests(t, OnDiskThreshold(0))
+}
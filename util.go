@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"os"
 )
 
 func calcMaxToBPV(max uint64) byte {
@@ -35,30 +34,35 @@ func calcBPVToMax(bpv byte) uint64 {
 	}
 }
 
-func removeFile(file File) error {
-	type namer interface{ Name() string }
-	name := file.(namer).Name()
-	if err := os.Remove(name); err != nil {
-		file.Close()
-		return err
+// unwrapArray returns the current representation behind a stable handle, so
+// that fast paths can recognize the concrete array type.  Code which goes on
+// to use the representation should hold the handle's lock; see lockImpl.
+func unwrapArray(ba BigArray) BigArray {
+	switch x := ba.(type) {
+	case *dynamicArray:
+		return x.impl
+	case *migratableNullableArray:
+		return x.nullableArray
+	default:
+		return ba
 	}
-	return file.Close()
 }
 
 func debugImpl(ba BigArray) string {
 	var buf bytes.Buffer
 	buf.WriteByte('[')
-	ForEach(ba, func(index uint64, value uint64) error {
-		if index > 0 {
+	iter := ba.Iterate(0, ba.Len())
+	for iter.Next() {
+		if iter.Index() > 0 {
 			buf.WriteByte(' ')
 		}
-		if value == ^uint64(0) {
-			buf.WriteByte('.')
+		if iter.Valid() {
+			fmt.Fprintf(&buf, "%d", iter.Value())
 		} else {
-			fmt.Fprintf(&buf, "%d", value)
+			buf.WriteByte('.')
 		}
-		return nil
-	})
+	}
+	iter.Close()
 	buf.WriteByte(']')
 	return buf.String()
 }